@@ -1,12 +1,16 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
 	"net"
 	"reflect"
 	"strconv"
-	"sync"
+	"time"
 )
 
 type Client struct {
@@ -14,20 +18,112 @@ type Client struct {
 	Db     int
 	MaxCon int
 
-	mu   sync.Mutex //  protect conns
-	cons []*RedisConn
+	opts   Options
+	ctx    context.Context
+	pool   *connPool   // shared by all clients derived by WithContext
+	dbs    *dbPools    // pools of WithDB, shared by all derived clients
+	pinned *pinnedConn // of Conn, the pool is not used
 }
 
+// WithContext returns a shallow copy of client, sharing its connections,
+// whose commands are bound to ctx: the deadline of ctx is applied to the
+// socket, and a cancelled command discards its connection.
+func (client *Client) WithContext(ctx context.Context) *Client {
+	if ctx == nil {
+		panic("nil context")
+	}
+	c := *client
+	c.ctx = ctx
+	return &c
+}
+
+// Context returns the context of the client, context.Background() by default.
+func (client *Client) Context() context.Context {
+	if client.ctx != nil {
+		return client.ctx
+	}
+	return context.Background()
+}
+
+// toBytes encodes values known to be encodable, see argBytes
 func toBytes(value interface{}) []byte {
+	b, err := argBytes(value)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// argBytes encodes an argument: strings and []byte as they are, numbers in
+// decimal, a time.Duration in nanoseconds, bools as 1 or 0, and nil as an
+// empty string. Other types are errors.
+func argBytes(value interface{}) ([]byte, error) {
 	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
 	case string:
-		return []byte(v)
+		return []byte(v), nil
 	case []byte:
-		return v
+		return v, nil
 	case int:
-		return []byte(strconv.Itoa(v))
+		return []byte(strconv.Itoa(v)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case float32:
+		return []byte(strconv.FormatFloat(float64(v), 'f', -1, 32)), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	}
+	// other integers, time.Duration, and named types, eg: type ID string
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.String:
+		return []byte(v.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []byte(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return []byte(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return []byte(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())), nil
+	case reflect.Bool:
+		return argBytes(v.Bool())
+	}
+	return nil, fmt.Errorf("Unsupported type %T of argument, only []byte, string, bool and numbers", value)
+}
+
+// valueBytes encodes strings, []byte, numbers and bools as argBytes, and
+// other values, eg: structs and nil, as json
+func valueBytes(value interface{}) ([]byte, error) {
+	if value != nil {
+		if b, err := argBytes(value); err == nil {
+			return b, nil
+		}
+	}
+	return json.Marshal(value)
+}
+
+func toArgs(values []interface{}) ([][]byte, error) {
+	args := make([][]byte, len(values))
+	for i, v := range values {
+		b, err := argBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = b
+	}
+	return args, nil
+}
+
+func keyArgs(key string, values []interface{}) ([][]byte, error) {
+	args, err := toArgs(values)
+	if err != nil {
+		return nil, err
 	}
-	panic("Only []byte, string is understandable")
+	return append([][]byte{[]byte(key)}, args...), nil
 }
 
 func copyBytes(b []byte) (r []byte) {
@@ -38,62 +134,230 @@ func copyBytes(b []byte) (r []byte) {
 }
 
 func (client *Client) returnCon(c *RedisConn) {
-	client.mu.Lock()
-	if len(client.cons) >= client.MaxCon {
-		c.conn.Close()
-	} else {
-		client.cons = append(client.cons, c)
+	if client.pinned == nil {
+		client.pool.put(c)
 	}
-	client.mu.Unlock()
 }
 
-func (client *Client) closeAll() {
-	client.mu.Lock()
-	for _, c := range client.cons {
+// removeCon closes a connection which is broken or in an unknown state
+func (client *Client) removeCon(c *RedisConn) {
+	if client.pinned == nil {
+		client.pool.remove(c)
+	} else { // removed from the pool by Conn.Close
+		client.pinned.broken = true
 		c.conn.Close()
 	}
-	client.cons = nil
-	client.mu.Unlock()
 }
 
 func (client *Client) getCon() (con *RedisConn, err error) {
-	client.mu.Lock()
-	if len(client.cons) > 0 {
-		con = client.cons[len(client.cons)-1]
-		client.cons = client.cons[0 : len(client.cons)-1]
-	}
-	client.mu.Unlock()
-	if con != nil {
-		return
+	if p := client.pinned; p != nil {
+		if p.c == nil {
+			return nil, ErrClosed
+		} else if p.broken {
+			return nil, ErrBadConn
+		}
+		return p.c, nil
 	}
+	return client.pool.get(client.Context())
+}
 
-	return client.openConn()
+func (client *Client) dial(ctx context.Context) (net.Conn, error) {
+	opts := &client.opts
+	if opts.Dialer != nil {
+		if opts.DialTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.DialTimeout)
+			defer cancel()
+		}
+		return opts.Dialer(ctx, opts.Network, client.Addr)
+	}
+	d := &net.Dialer{Timeout: opts.DialTimeout}
+	if opts.TLSConfig != nil {
+		td := tls.Dialer{NetDialer: d, Config: opts.TLSConfig}
+		return td.DialContext(ctx, opts.Network, client.Addr)
+	}
+	return d.DialContext(ctx, opts.Network, client.Addr)
 }
 
-func (client *Client) openConn() (*RedisConn, error) {
-	c, err := net.Dial("tcp", client.Addr)
+func (client *Client) openConn(ctx context.Context) (*RedisConn, error) {
+	c, err := client.dial(ctx)
 	if err == nil {
 		rb := &ByteBuffer{buffer: make([]byte, BufferSize)}
 		wb := &ByteBuffer{buffer: make([]byte, BufferSize)}
-		c := &RedisConn{conn: c, rbuf: rb, wbuf: wb}
-		if client.Db > 0 {
-			c.send("SELECT", false, []byte(strconv.Itoa(client.Db)))
+		now := time.Now()
+		c := &RedisConn{conn: c, rbuf: rb, wbuf: wb, createdAt: now, usedAt: now,
+			readTimeout: client.opts.ReadTimeout, writeTimeout: client.opts.WriteTimeout,
+			onPush: client.opts.OnPush}
+		stop := c.watch(ctx)
+		err := client.initConn(c)
+		if stop() { // the deadline may be left in the past
+			err = ctxErr(ctx)
 		}
-		if client.MaxCon == 0 {
-			client.MaxCon = DefaultMaxCon
+		if err != nil {
+			c.conn.Close()
+			return nil, err
 		}
 		return c, nil
 	}
-	return nil, err
+	return nil, wrapTimeout("dial", err)
+}
+
+// initConn authenticates a new connection, names it and selects the db
+func (client *Client) initConn(c *RedisConn) error {
+	opts := &client.opts
+	var cmds [][][]byte     // command and args
+	if opts.Protocol != 2 { // HELLO authenticates and names as well
+		hello := [][]byte{[]byte("HELLO"), toBytes(opts.Protocol)}
+		if opts.Password != "" {
+			user := opts.Username
+			if user == "" {
+				user = "default"
+			}
+			hello = append(hello, []byte("AUTH"), []byte(user), []byte(opts.Password))
+		}
+		if opts.ClientName != "" {
+			hello = append(hello, []byte("SETNAME"), []byte(opts.ClientName))
+		}
+		cmds = append(cmds, hello)
+	} else {
+		if opts.Password != "" {
+			auth := [][]byte{[]byte("AUTH"), []byte(opts.Password)}
+			if opts.Username != "" {
+				auth = [][]byte{auth[0], []byte(opts.Username), auth[1]}
+			}
+			cmds = append(cmds, auth)
+		}
+		if opts.ClientName != "" {
+			cmds = append(cmds, [][]byte{[]byte("CLIENT"), []byte("SETNAME"), []byte(opts.ClientName)})
+		}
+	}
+	if client.Db > 0 {
+		cmds = append(cmds, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(client.Db))})
+	}
+
+	for _, cmd := range cmds {
+		if _, err := c.send(string(cmd[0]), false, cmd[1:]...); err != nil {
+			return fmt.Errorf("%s on connect: %w", cmd[0], err)
+		}
+	}
+	if opts.OnConnect != nil {
+		if err := opts.OnConnect(c); err != nil {
+			return fmt.Errorf("OnConnect: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stats returns the statistics of the connection pool
+func (client *Client) Stats() PoolStats {
+	return client.pool.stats()
+}
+
+// Close closes the idle connections, connections in use are closed when
+// returned. Commands fail with ErrClosed afterwards. Clients derived by
+// WithDB are closed as well.
+func (client *Client) Close() error {
+	return client.dbs.close()
 }
 
 func (client *Client) sendCommand(cmd string, newRbuf bool, args ...[]byte) (interface{}, error) {
+	return client.sendBlocking(0, cmd, newRbuf, args...)
+}
+
+// read-only commands, safe to send again if the connection breaks before
+// the reply. A write may have been executed, its reply is lost.
+var retryable = map[string]bool{
+	"PING": true, "ECHO": true, "DBSIZE": true, "EXISTS": true, "TYPE": true,
+	"TTL": true, "PTTL": true, "RANDOMKEY": true, "OBJECT": true,
+	"GET": true, "MGET": true, "STRLEN": true, "GETRANGE": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HKEYS": true, "HVALS": true,
+	"HLEN": true, "HEXISTS": true, "HSTRLEN": true,
+	"LRANGE": true, "LLEN": true, "LINDEX": true, "LPOS": true,
+	"SMEMBERS": true, "SCARD": true, "SISMEMBER": true, "SMISMEMBER": true,
+	"SINTER": true, "SUNION": true, "SDIFF": true, "SINTERCARD": true,
+	"ZSCORE": true, "ZMSCORE": true, "ZRANK": true, "ZREVRANK": true, "ZCARD": true,
+	"ZCOUNT": true, "ZLEXCOUNT": true, "ZRANGE": true, "ZRANGEBYSCORE": true,
+	"ZREVRANGE": true, "ZREVRANGEBYSCORE": true, "ZRANGEBYLEX": true,
+	"XRANGE": true, "XREVRANGE": true, "XLEN": true,
+	"SCAN": true, "SSCAN": true, "HSCAN": true, "ZSCAN": true,
+	"EVAL_RO": true, "EVALSHA_RO": true,
+}
+
+// shouldRetry reports whether err is caused by the network, or the server
+// is temporarily unavailable, and the command may be sent again
+func (client *Client) shouldRetry(cmd string, err error) bool {
+	if err == ErrClosed || err == ErrPoolTimeout || err == ErrBadConn {
+		return false
+	}
+	switch err.(type) {
+	case RedisError:
+		if !IsRetryable(err) {
+			return false
+		}
+	case ProtocolError:
+		return false
+	}
+	// a new connection loses the state of a pinned one
+	return retryable[cmd] && client.pinned == nil && ctxErr(client.Context()) == nil
+}
+
+// retryBackoff returns the time to wait before the nth retry, doubled each
+// time from MinRetryBackoff up to MaxRetryBackoff, with jitter
+func (client *Client) retryBackoff(n int) time.Duration {
+	d := client.opts.MinRetryBackoff << uint(n)
+	if d <= 0 || d > client.opts.MaxRetryBackoff {
+		d = client.opts.MaxRetryBackoff
+	}
+	if d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	return d
+}
+
+func (client *Client) sendBlocking(block time.Duration, cmd string, newRbuf bool, args ...[]byte) (interface{}, error) {
+	r, err := client.roundTrip(block, cmd, newRbuf, args...)
+	for i := 0; i < client.opts.MaxRetries && err != nil && client.shouldRetry(cmd, err); i++ {
+		timer := time.NewTimer(client.retryBackoff(i))
+		select {
+		case <-timer.C:
+		case <-client.Context().Done():
+			timer.Stop()
+			return nil, err
+		}
+		r, err = client.roundTrip(block, cmd, newRbuf, args...)
+	}
+	return r, err
+}
+
+// roundTrip sends a blocking command, see RedisConn.readReply for block.
+// The connection is returned to the pool unless broken.
+func (client *Client) roundTrip(block time.Duration, cmd string, newRbuf bool, args ...[]byte) (interface{}, error) {
+	ctx := client.Context()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if m := client.pool.mux; m != nil && block == 0 && client.pinned == nil &&
+		!stateful[cmd] && !blocking[cmd] {
+		return m.do(ctx, cmd, args)
+	}
 	if c, err := client.getCon(); err != nil {
 		return nil, err
 	} else {
-		r, err := c.send(cmd, newRbuf, args...)
-		if err == nil { // TODO, only network, retry if network error
+		stop := c.watch(ctx)
+		r, err := c.sendBlocking(block, cmd, newRbuf, args...)
+		if stop() || (err != nil && ctxErr(ctx) != nil) { // interrupted by ctx, maybe half-read
+			client.removeCon(c)
+			if err != nil {
+				err = ctxErr(ctx)
+			}
+		} else if _, ok := err.(RedisError); !ok && err != nil { // network or protocol error
+			client.removeCon(c)
+		} else if !stateful[cmd] {
 			client.returnCon(c)
+		} else if client.pinned != nil {
+			c.dirty = true
+		} else { // never reuse it, the state of it is changed
+			client.removeCon(c)
 		}
 		return r, err
 	}
@@ -124,6 +388,52 @@ func (client *Client) listCommand(cmd string, args ...[]byte) ([]string, error)
 	return rets, nil
 }
 
+// scan sends a command of the SCAN family: [key] cursor [MATCH match]
+// [COUNT count] [TYPE typ], and returns the elements and the next cursor,
+// 0 once done
+func (client *Client) scan(cmd, key string, cursor uint64, match string, count int64,
+	typ string) ([]string, uint64, error) {
+	var args [][]byte
+	if key != "" {
+		args = append(args, []byte(key))
+	}
+	args = append(args, toBytes(cursor))
+	if match != "" {
+		args = append(args, []byte("MATCH"), []byte(match))
+	}
+	if count > 0 {
+		args = append(args, []byte("COUNT"), toBytes(count))
+	}
+	if typ != "" {
+		args = append(args, []byte("TYPE"), []byte(typ))
+	}
+	values, err := Values(client.sendCommand(cmd, true, args...))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(values) != 2 {
+		return nil, 0, fmt.Errorf("Unexpected reply of %s %v", cmd, values)
+	}
+	next, err := String(values[0], nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if cursor, err = strconv.ParseUint(next, 10, 64); err != nil {
+		return nil, 0, err
+	}
+	elements, err := Strings(values[1], nil)
+	return elements, cursor, err
+}
+
+// blockFor returns the block of sendBlocking for the timeout in seconds of a
+// blocking command, 0 means blocking indefinitely
+func blockFor(seconds int) time.Duration {
+	if seconds == 0 {
+		return -1
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 func (client *Client) blockPop(cmd string, key interface{}, seconds int) ([]byte, string, error) {
 	var args [][]byte
 	switch v := key.(type) {
@@ -138,7 +448,7 @@ func (client *Client) blockPop(cmd string, key interface{}, seconds int) ([]byte
 	}
 	args = append(args, toBytes(seconds))
 
-	value, err := client.sendCommand(cmd, true, args...)
+	value, err := client.sendBlocking(blockFor(seconds), cmd, true, args...)
 	if err != nil {
 		return nil, "", err
 	}
@@ -150,18 +460,17 @@ func (client *Client) blockPop(cmd string, key interface{}, seconds int) ([]byte
 	return nil, "", nil
 }
 
-func (client *Client) listPush(cmd string, key string, values []interface{}) (int, error) {
-	args := make([][]byte, len(values)+1)
-	args[0] = []byte(key)
-	for i, v := range values {
-		args[i+1] = toBytes(v)
+func (client *Client) listPush(cmd string, key string, values []interface{}) (int64, error) {
+	args, err := keyArgs(key, values)
+	if err != nil {
+		return 0, err
 	}
 	value, err := client.sendCommand(cmd, false, args...)
 	if err != nil {
 		return 0, err
 	}
 
-	return value.(int), nil
+	return value.(int64), nil
 }
 
 func mappingToArgs(v reflect.Value, args *[][]byte) error {
@@ -177,7 +486,7 @@ func mappingToArgs(v reflect.Value, args *[][]byte) error {
 		keys := v.MapKeys()
 		for _, k := range keys {
 			*args = append(*args, []byte(k.String()))
-			bs, err := json.Marshal(v.MapIndex(k).Interface())
+			bs, err := valueBytes(v.MapIndex(k).Interface())
 			if err != nil {
 				return err
 			}
@@ -189,9 +498,18 @@ func mappingToArgs(v reflect.Value, args *[][]byte) error {
 }
 
 func NewClient(addr string, db int) (*Client, error) {
-	client := &Client{Addr: addr, Db: db}
+	return NewClientWithOptions(&Options{Addr: addr, Db: db})
+}
+
+func NewClientWithOptions(opts *Options) (*Client, error) {
+	client := &Client{opts: *opts}
+	client.opts.init()
+	client.Addr, client.Db, client.MaxCon = client.opts.Addr, client.opts.Db, client.opts.MaxCon
+	client.pool = newConnPool(&client.opts, client.openConn)
+	client.dbs = &dbPools{pools: map[int]*connPool{client.Db: client.pool}}
 	c, err := client.getCon()
 	if err != nil {
+		client.Close()
 		return nil, err
 	} else {
 		client.returnCon(c)
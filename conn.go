@@ -1,10 +1,13 @@
 package redis
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"math/big"
 	"net"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var needNewReadBuffer map[string]bool = map[string]bool{
@@ -22,6 +25,88 @@ type RedisConn struct {
 	conn net.Conn
 	rbuf *ByteBuffer
 	wbuf *ByteBuffer
+
+	readTimeout, writeTimeout time.Duration
+	hasReadDeadline           bool            // set, to be cleared if no timeout
+	hasWriteDeadline          bool            // written and read by separate goroutines of mux
+	ctx                       context.Context // set by watch
+
+	createdAt, usedAt time.Time
+	onPush            func(Push)
+	dirty             bool // the state is changed, eg: by SELECT
+}
+
+// Push is an out of band message of RESP3, eg: [invalidate [key1 key2]],
+// the first element is its kind
+type Push []interface{}
+
+func (p Push) Kind() string {
+	if len(p) > 0 {
+		if kind, ok := p[0].([]byte); ok {
+			return string(kind)
+		}
+	}
+	return ""
+}
+
+// a deadline in the past, to unblock pending reads and writes at once
+var aLongTimeAgo = time.Unix(1, 0)
+
+// watch binds the connection to ctx until the returned stop func is called:
+// the deadline of ctx caps the I/O timeouts, and pending I/O is interrupted
+// when ctx is done. stop reports whether ctx interrupted the connection,
+// which then may be half-read and should be discarded.
+func (c *RedisConn) watch(ctx context.Context) (stop func() bool) {
+	if ctx.Done() == nil { // never canceled, eg: context.Background()
+		return func() bool { return false }
+	}
+	c.ctx = ctx
+	stopf := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(aLongTimeAgo)
+	})
+	return func() bool {
+		c.ctx = nil
+		return !stopf()
+	}
+}
+
+// ctxErr returns the error of ctx, or DeadlineExceeded once its deadline is
+// past: a socket deadline copied from ctx may fire before ctx is done
+func ctxErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// setDeadline sets the deadline of the next read or write, now + timeout,
+// capped by the deadline of the bound context. timeout <= 0 means no limit.
+func (c *RedisConn) setDeadline(write bool, timeout time.Duration) error {
+	set, has := c.conn.SetReadDeadline, &c.hasReadDeadline
+	if write {
+		set, has = c.conn.SetWriteDeadline, &c.hasWriteDeadline
+	}
+	var t time.Time
+	if timeout > 0 {
+		t = time.Now().Add(timeout)
+	}
+	if c.ctx != nil {
+		if d, ok := c.ctx.Deadline(); ok && (t.IsZero() || d.Before(t)) {
+			t = d
+		}
+	}
+	if !t.IsZero() || *has {
+		set(t)
+		*has = !t.IsZero()
+	}
+	if c.ctx != nil {
+		// canceled before set, the deadline of watch may be overridden
+		return c.ctx.Err()
+	}
+	return nil
 }
 
 func (p *ByteBuffer) moreSpace(n int, write bool) {
@@ -34,7 +119,7 @@ func (p *ByteBuffer) moreSpace(n int, write bool) {
 		if write {
 			copy(tmp, p.buffer[:p.pos]) // for write buffer
 		} else {
-			copy(tmp, p.buffer[p.pos:p.limit])
+			copy(tmp, p.buffer[:p.limit]) // keep pos, slices read are still valid
 		}
 		p.buffer = tmp
 	}
@@ -88,11 +173,30 @@ func (buf *ByteBuffer) encodeRequest(cmd string, args [][]byte) {
 	}
 }
 
+// compact moves the unread bytes to the start, for a buffer read continuously
+func (buf *ByteBuffer) compact() {
+	n := copy(buf.buffer, buf.buffer[buf.pos:buf.limit])
+	buf.pos, buf.limit = 0, n
+}
+
+// appendRequest encodes a request after the ones buffered, for pipelining
+func (buf *ByteBuffer) appendRequest(cmd string, args [][]byte) {
+	buf.moreSpace(16, true)
+	buf.buffer[buf.pos] = '*'
+	buf.pos += 1
+	buf.writeInt(len(args) + 1)
+	buf.writeBytes([]byte(cmd))
+
+	for _, s := range args {
+		buf.writeBytes(s)
+	}
+}
+
 func (c *RedisConn) readMore() error {
 	n, err := c.conn.Read(c.rbuf.buffer[c.rbuf.limit:])
 	c.rbuf.limit += n
 	if err != nil {
-		return err
+		return wrapTimeout("read", err)
 	}
 	return nil
 }
@@ -117,6 +221,54 @@ func (c *RedisConn) readLine() ([]byte, error) {
 	return c.rbuf.buffer[start : c.rbuf.pos-2], nil
 }
 
+// readBulk reads length bytes and the trailing \r\n
+func (c *RedisConn) readBulk(length int) ([]byte, error) {
+	c.rbuf.moreSpace(length+2, false)
+	for c.rbuf.pos+length+2 > c.rbuf.limit {
+		err := c.readMore()
+		if err != nil {
+			return nil, err
+		}
+	}
+	start := c.rbuf.pos
+	c.rbuf.pos += length + 2
+	return c.rbuf.buffer[start : c.rbuf.pos-2], nil
+}
+
+// readElements reads n replies of an aggregate. An error reply of an element,
+// eg: of EXEC, is kept in place as a RedisError, and the first of them is
+// returned after all of them are read: the connection is still usable.
+func (c *RedisConn) readElements(n int) ([]interface{}, error) {
+	var e error
+	rets := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		v, err := c.readResponse()
+		if _, ok := err.(RedisError); ok {
+			if e == nil {
+				e = err
+			}
+			if v == nil {
+				v = err
+			}
+		} else if err != nil {
+			return nil, err
+		}
+		rets[i] = v
+	}
+	return rets, e
+}
+
+// mapKey converts a key of a RESP3 map, which is usually a bulk string
+func mapKey(k interface{}) string {
+	switch v := k.(type) {
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(k)
+}
+
 func (c *RedisConn) readResponse() (interface{}, error) {
 	if c.rbuf.pos == c.rbuf.limit {
 		c.rbuf.moreSpace(128, false)
@@ -138,10 +290,10 @@ func (c *RedisConn) readResponse() (interface{}, error) {
 		return line, nil
 		//  Error Reply, eg: -ERR unknown command 'foobar'
 	case '-':
-		return nil, errors.New(string(line))
+		return nil, RedisError(line)
 		//  Integer Reply, eg: 1
 	case ':':
-		return strconv.Atoi(string(line))
+		return strconv.ParseInt(string(line), 10, 64)
 		// Bulk replies, $6\r\nfoobar\r\n
 	case '$':
 		length, err := strconv.Atoi(string(line))
@@ -149,61 +301,154 @@ func (c *RedisConn) readResponse() (interface{}, error) {
 			return nil, err
 		}
 		if length > 0 {
-			c.rbuf.moreSpace(length+2, false)
-			for c.rbuf.pos+length+2 > c.rbuf.limit {
-				err := c.readMore()
-				if err != nil {
-					return nil, err
-				}
-			}
-			start := c.rbuf.pos
-			c.rbuf.pos += length + 2
-			return c.rbuf.buffer[start : c.rbuf.pos-2], nil
+			return c.readBulk(length)
 		} else if length == 0 {
 			return c.readLine()
 		} else {
 			// NULL Bulk Reply, do not return an empty string, but a nil object,
 			return nil, nil
 		}
-		// Multi-bulk replies, LRANGE mylist 0 3
-	case '*':
+		// Multi-bulk replies, LRANGE mylist 0 3. RESP3 sets are the same, eg: ~2
+		// RESP3 push messages are out of band, like pub/sub messages, eg: >3
+	case '*', '~', '>':
 		size, err := strconv.Atoi(string(line))
 		if err != nil {
-			return nil, RedisError("MultiBulk reply expected a number")
+			return nil, ProtocolError("MultiBulk reply expected a number")
 		}
 		if size > 0 {
-			rets := make([]interface{}, size)
-			for i := 0; i < size; i++ {
-				rets[i], err = c.readResponse()
-				if err != nil {
-					return nil, err
-				}
+			rets, err := c.readElements(size)
+			if _, ok := err.(RedisError); err != nil && !ok {
+				return nil, err
+			}
+			if c.rbuf.buffer[pos] == '>' {
+				return Push(rets), err
 			}
-			return rets, nil
+			return rets, err
 		} else {
 			return nil, nil
 		}
+		// RESP3 null, _\r\n
+	case '_':
+		return nil, nil
+		// RESP3 double, eg: ,3.14 ,inf ,nan
+	case ',':
+		return strconv.ParseFloat(string(line), 64)
+		// RESP3 boolean, #t or #f
+	case '#':
+		return len(line) == 1 && line[0] == 't', nil
+		// RESP3 big number, eg: (3492890328409238509324850943850943825024385
+	case '(':
+		n, ok := new(big.Int).SetString(string(line), 10)
+		if !ok {
+			return nil, ProtocolError("Invalid big number " + string(line))
+		}
+		return n, nil
+		// RESP3 blob error, !21\r\nSYNTAX invalid syntax\r\n
+		// RESP3 verbatim string, =15\r\ntxt:Some string\r\n
+	case '!', '=':
+		length, err := strconv.Atoi(string(line))
+		if err != nil {
+			return nil, err
+		}
+		bs, err := c.readBulk(length)
+		if err != nil {
+			return nil, err
+		}
+		if c.rbuf.buffer[pos] == '!' {
+			return nil, RedisError(bs)
+		}
+		if len(bs) >= 4 && bs[3] == ':' {
+			bs = bs[4:] // the format, eg: txt: mkd:
+		}
+		return bs, nil
+		// RESP3 map, eg: HGETALL, %2. Attributes, eg: |1, precede a reply,
+		// they are auxiliary data, and are skipped
+	case '%', '|':
+		size, err := strconv.Atoi(string(line))
+		if err != nil {
+			return nil, ProtocolError("Map reply expected a number")
+		}
+		kvs, err := c.readElements(size * 2)
+		if _, ok := err.(RedisError); err != nil && !ok {
+			return nil, err
+		}
+		if c.rbuf.buffer[pos] == '|' {
+			return c.readResponse()
+		}
+		m := make(map[string]interface{}, size)
+		for i := 0; i < len(kvs); i += 2 {
+			m[mapKey(kvs[i])] = kvs[i+1]
+		}
+		return m, err
 	}
-	return nil, fmt.Errorf("Unkown %s", c.rbuf.buffer[0:1])
+	return nil, ProtocolError(fmt.Sprintf("Unknown reply type %q", c.rbuf.buffer[pos]))
 }
 
-func (c *RedisConn) send(cmd string, rbuf bool, args ...[]byte) (interface{}, error) {
-	c.wbuf.encodeRequest(cmd, args) // reuse wbuf
+// flush writes out the write buffer
+func (c *RedisConn) flush() error {
+	if err := c.setDeadline(true, c.writeTimeout); err != nil {
+		return err
+	}
 	pos := 0
 	for pos < c.wbuf.pos {
 		n, err := c.conn.Write(c.wbuf.buffer[pos:c.wbuf.pos])
 		if err != nil {
-			return nil, err
+			return wrapTimeout("write", err)
 		}
 		pos += n
 	}
+	return nil
+}
+
+// readReply reads a response, the read timeout is extended by block for
+// blocking commands, block < 0 disables it: the command may block forever
+func (c *RedisConn) readReply(block time.Duration) (interface{}, error) {
+	timeout := c.readTimeout
+	if block < 0 {
+		timeout = 0
+	} else if timeout > 0 {
+		timeout += block
+	}
+	if err := c.setDeadline(false, timeout); err != nil {
+		return nil, err
+	}
+	for {
+		r, err := c.readResponse()
+		if p, ok := r.(Push); ok { // RESP3, eg: invalidation of client tracking
+			if c.onPush != nil {
+				c.onPush(p)
+			}
+			continue
+		}
+		return r, err
+	}
+}
+
+// Do sends a command on the connection, like Client.Do. For Options.OnConnect
+func (c *RedisConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	bs, err := toArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(strings.ToUpper(cmd), true, bs...)
+}
+
+func (c *RedisConn) send(cmd string, rbuf bool, args ...[]byte) (interface{}, error) {
+	return c.sendBlocking(0, cmd, rbuf, args...)
+}
+
+func (c *RedisConn) sendBlocking(block time.Duration, cmd string, rbuf bool, args ...[]byte) (interface{}, error) {
+	c.wbuf.encodeRequest(cmd, args) // reuse wbuf
+	if err := c.flush(); err != nil {
+		return nil, err
+	}
 	if rbuf { // using a new buffer, avoid copy
 		old := c.rbuf
 		c.rbuf = &ByteBuffer{buffer: make([]byte, BufferSize)}
 		defer func() { c.rbuf = old }() // restore
-		return c.readResponse()
+		return c.readReply(block)
 	} else {
 		c.rbuf.pos, c.rbuf.limit = 0, 0
-		return c.readResponse()
+		return c.readReply(block)
 	}
 }
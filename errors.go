@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// RedisError is an error replied by the server, the connection is still
+// usable. It starts with an error code, eg: WRONGTYPE Operation against a
+// key holding the wrong kind of value. Errors of the client, eg: ErrClosed
+// and ErrPoolTimeout, are not RedisError.
+type RedisError string
+
+func (err RedisError) Error() string { return "Redis Error: " + string(err) }
+
+// Code returns the error code, eg: ERR, WRONGTYPE, NOSCRIPT, or "" if none
+func (err RedisError) Code() string {
+	code, _, _ := strings.Cut(string(err), " ")
+	if code == "" {
+		return ""
+	}
+	for i := 0; i < len(code); i++ {
+		if (code[i] < 'A' || code[i] > 'Z') && code[i] != '_' && code[i] != '-' {
+			return ""
+		}
+	}
+	return code
+}
+
+// Message returns the error without the code
+func (err RedisError) Message() string {
+	if code := err.Code(); code != "" {
+		return strings.TrimPrefix(string(err)[len(code):], " ")
+	}
+	return string(err)
+}
+
+// Redirect parses a MOVED or ASK error of Redis Cluster, eg: MOVED 3999 127.0.0.1:6381
+func (err RedisError) Redirect() (slot int, addr string, ok bool) {
+	if code := err.Code(); code != "MOVED" && code != "ASK" {
+		return 0, "", false
+	}
+	fields := strings.Fields(err.Message())
+	if len(fields) != 2 {
+		return 0, "", false
+	}
+	slot, e := strconv.Atoi(fields[0])
+	if e != nil {
+		return 0, "", false
+	}
+	return slot, fields[1], true
+}
+
+// ProtocolError is a malformed reply, the connection is discarded
+type ProtocolError string
+
+func (err ProtocolError) Error() string { return "Redis Protocol Error: " + string(err) }
+
+var KeyDoesNotExist = RedisError("Key does not exist")
+
+type TimeoutError struct {
+	Op  string // dial, read or write
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return "Redis Error: " + e.Op + " timeout: " + e.Err.Error()
+}
+
+func (e *TimeoutError) Timeout() bool   { return true }
+func (e *TimeoutError) Temporary() bool { return true }
+func (e *TimeoutError) Unwrap() error   { return e.Err }
+
+func wrapTimeout(op string, err error) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return &TimeoutError{Op: op, Err: err}
+	}
+	return err
+}
+
+// ErrorCode returns the code of a server error in the chain of err, or ""
+func ErrorCode(err error) string {
+	var e RedisError
+	if errors.As(err, &e) {
+		return e.Code()
+	}
+	return ""
+}
+
+// IsWrongType reports whether err is caused by a key holding the wrong kind of value
+func IsWrongType(err error) bool { return ErrorCode(err) == "WRONGTYPE" }
+
+// IsNoScript reports whether EVALSHA failed because the script is not loaded
+func IsNoScript(err error) bool { return ErrorCode(err) == "NOSCRIPT" }
+
+// IsReadOnly reports whether a write is sent to a replica
+func IsReadOnly(err error) bool { return ErrorCode(err) == "READONLY" }
+
+// IsLoading reports whether the server is loading the dataset in memory
+func IsLoading(err error) bool { return ErrorCode(err) == "LOADING" }
+
+// IsBusy reports whether the server is busy running a script or function
+func IsBusy(err error) bool { return ErrorCode(err) == "BUSY" }
+
+// IsMoved reports whether a cluster key moved to addr, permanently
+func IsMoved(err error) (addr string, ok bool) { return redirect(err, "MOVED") }
+
+// IsAsk reports whether a cluster key is migrating to addr
+func IsAsk(err error) (addr string, ok bool) { return redirect(err, "ASK") }
+
+func redirect(err error, code string) (string, bool) {
+	var e RedisError
+	if errors.As(err, &e) && e.Code() == code {
+		_, addr, ok := e.Redirect()
+		return addr, ok
+	}
+	return "", false
+}
+
+// IsRetryable reports whether the command may succeed if sent again later:
+// the server is temporarily unable to serve it
+func IsRetryable(err error) bool {
+	switch ErrorCode(err) {
+	case "LOADING", "BUSY", "TRYAGAIN", "MASTERDOWN", "CLUSTERDOWN":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package redis
+
+import "reflect"
+
+func (client *Client) Hgetall(key string) (m map[string]string, err error) {
+	return StringMap(client.sendCommand("HGETALL", true, []byte(key)))
+}
+
+// Hmset sets fields of mapping, values are encoded as by toBytes, or as json
+// if not a string, []byte, number or bool
+func (client *Client) Hmset(key string, mapping map[string]interface{}) error {
+	args := [][]byte{[]byte(key)}
+	if err := mappingToArgs(reflect.ValueOf(mapping), &args); err != nil {
+		return err
+	}
+	return client.simple("HMSET", args...)
+}
+
+// Hset sets fields like Hmset, and returns the number of fields added
+func (client *Client) Hset(key string, mapping map[string]interface{}) (int64, error) {
+	args := [][]byte{[]byte(key)}
+	if err := mappingToArgs(reflect.ValueOf(mapping), &args); err != nil {
+		return 0, err
+	}
+	return Int64(client.sendCommand("HSET", false, args...))
+}
+
+// Hsetnx sets the field only if not exists, returns whether it's set
+func (client *Client) Hsetnx(key, field string, value interface{}) (bool, error) {
+	return Bool(client.sendCommand("HSETNX", false, []byte(key), []byte(field), toBytes(value)))
+}
+
+// Hget returns KeyDoesNotExist if the key or the field does not exist
+func (client *Client) Hget(key, field string) ([]byte, error) {
+	return Bytes(client.sendCommand("HGET", true, []byte(key), []byte(field)))
+}
+
+// Hmget returns the values of fields, nil for those not exist
+func (client *Client) Hmget(key string, fields ...string) ([][]byte, error) {
+	args := append([][]byte{[]byte(key)}, stringArgs(fields)...)
+	values, err := Values(client.sendCommand("HMGET", true, args...))
+	if err != nil {
+		return nil, err
+	}
+	rets := make([][]byte, len(values))
+	for i, v := range values {
+		rets[i], _ = v.([]byte)
+	}
+	return rets, nil
+}
+
+// Hdel returns the number of fields deleted
+func (client *Client) Hdel(key string, fields ...string) (int64, error) {
+	args := append([][]byte{[]byte(key)}, stringArgs(fields)...)
+	return Int64(client.sendCommand("HDEL", false, args...))
+}
+
+func (client *Client) Hexists(key, field string) (bool, error) {
+	return Bool(client.sendCommand("HEXISTS", false, []byte(key), []byte(field)))
+}
+
+func (client *Client) Hlen(key string) (int64, error) {
+	return Int64(client.sendCommand("HLEN", false, []byte(key)))
+}
+
+func (client *Client) Hkeys(key string) ([]string, error) {
+	return Strings(client.sendCommand("HKEYS", true, []byte(key)))
+}
+
+func (client *Client) Hvals(key string) ([]string, error) {
+	return Strings(client.sendCommand("HVALS", true, []byte(key)))
+}
+
+// Hincrby returns the value after the increment
+func (client *Client) Hincrby(key, field string, incr int64) (int64, error) {
+	return Int64(client.sendCommand("HINCRBY", false, []byte(key), []byte(field), toBytes(incr)))
+}
+
+func (client *Client) Hincrbyfloat(key, field string, incr float64) (float64, error) {
+	return Float64(client.sendCommand("HINCRBYFLOAT", true, []byte(key), []byte(field), toBytes(incr)))
+}
+
+// Hstrlen returns the length of the value, 0 if the field does not exist
+func (client *Client) Hstrlen(key, field string) (int64, error) {
+	return Int64(client.sendCommand("HSTRLEN", false, []byte(key), []byte(field)))
+}
+
+// Hrandfield returns up to count distinct random fields, or count fields
+// which may repeat if count is negative
+func (client *Client) Hrandfield(key string, count int) ([]string, error) {
+	return Strings(client.sendCommand("HRANDFIELD", true, []byte(key), toBytes(count)))
+}
+
+// Hscan returns field value pairs of an iteration, and the next cursor,
+// which is 0 once done. Start with the cursor 0. match, if not empty,
+// filters fields, eg: user:*. count is a hint of redis, 10 if 0.
+func (client *Client) Hscan(key string, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	return client.scan("HSCAN", key, cursor, match, count, "")
+}
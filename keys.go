@@ -0,0 +1,166 @@
+package redis
+
+import "time"
+
+// Returned by Ttl and Pttl if the key does not exist, or has no TTL
+const (
+	NoKey    time.Duration = -2
+	NoExpire time.Duration = -1
+)
+
+// Conditions of the EXPIRE family, redis 7.0: NX expires only if the key has
+// no TTL, XX only if it has one, GT and LT only if the new TTL is greater or
+// less than the current one
+const (
+	ExpireNX = "NX"
+	ExpireXX = "XX"
+	ExpireGT = "GT"
+	ExpireLT = "LT"
+)
+
+// Del returns the number of keys deleted
+func (client *Client) Del(keys ...string) (int64, error) {
+	return Int64(client.sendCommand("DEL", false, stringArgs(keys)...))
+}
+
+// Unlink deletes keys like Del, the memory is reclaimed in the background
+func (client *Client) Unlink(keys ...string) (int64, error) {
+	return Int64(client.sendCommand("UNLINK", false, stringArgs(keys)...))
+}
+
+// Exists returns the number of keys exist, a key is counted as many times
+// as repeated
+func (client *Client) Exists(keys ...string) (int64, error) {
+	return Int64(client.sendCommand("EXISTS", false, stringArgs(keys)...))
+}
+
+// Touch updates the last access time of keys, returns the number exist
+func (client *Client) Touch(keys ...string) (int64, error) {
+	return Int64(client.sendCommand("TOUCH", false, stringArgs(keys)...))
+}
+
+func (client *Client) expire(cmd, key string, arg int64, cond []string) (bool, error) {
+	args := append([][]byte{[]byte(key), toBytes(arg)}, stringArgs(cond)...)
+	return Bool(client.sendCommand(cmd, false, args...))
+}
+
+// Expire sets the TTL of key, by PEXPIRE if not whole seconds. It returns
+// false if the key does not exist, or cond, one of ExpireNX, ExpireXX,
+// ExpireGT and ExpireLT, is not met.
+func (client *Client) Expire(key string, ttl time.Duration, cond ...string) (bool, error) {
+	if ttl%time.Second != 0 {
+		return client.Pexpire(key, ttl, cond...)
+	}
+	return client.expire("EXPIRE", key, int64(ttl/time.Second), cond)
+}
+
+// Pexpire is Expire in milliseconds, a sub millisecond ttl is rounded up
+func (client *Client) Pexpire(key string, ttl time.Duration, cond ...string) (bool, error) {
+	ms := ttl.Milliseconds()
+	if ttl%time.Millisecond > 0 {
+		ms++
+	}
+	return client.expire("PEXPIRE", key, ms, cond)
+}
+
+// Expireat expires key at, by PEXPIREAT if not whole seconds, see Expire
+func (client *Client) Expireat(key string, at time.Time, cond ...string) (bool, error) {
+	if at.Nanosecond() != 0 {
+		return client.Pexpireat(key, at, cond...)
+	}
+	return client.expire("EXPIREAT", key, at.Unix(), cond)
+}
+
+func (client *Client) Pexpireat(key string, at time.Time, cond ...string) (bool, error) {
+	return client.expire("PEXPIREAT", key, at.UnixMilli(), cond)
+}
+
+// Ttl returns the TTL of key in seconds, NoKey or NoExpire
+func (client *Client) Ttl(key string) (time.Duration, error) {
+	n, err := Int64(client.sendCommand("TTL", false, []byte(key)))
+	return ttl(n, time.Second), err
+}
+
+// Pttl returns the TTL of key in milliseconds, NoKey or NoExpire
+func (client *Client) Pttl(key string) (time.Duration, error) {
+	n, err := Int64(client.sendCommand("PTTL", false, []byte(key)))
+	return ttl(n, time.Millisecond), err
+}
+
+func ttl(n int64, unit time.Duration) time.Duration {
+	if n < 0 { // -2 and -1 are NoKey and NoExpire
+		return time.Duration(n)
+	}
+	return time.Duration(n) * unit
+}
+
+// Persist removes the TTL, returns false if the key has none, or not exists
+func (client *Client) Persist(key string) (bool, error) {
+	return Bool(client.sendCommand("PERSIST", false, []byte(key)))
+}
+
+// Type returns the type of key: string, list, set, zset, hash, stream, or
+// none if not exists
+func (client *Client) Type(key string) (string, error) {
+	return String(client.sendCommand("TYPE", true, []byte(key)))
+}
+
+// Rename returns an error if key does not exist
+func (client *Client) Rename(key, newKey string) error {
+	return client.simple("RENAME", []byte(key), []byte(newKey))
+}
+
+// Renamenx renames only if newKey does not exist, returns whether renamed
+func (client *Client) Renamenx(key, newKey string) (bool, error) {
+	return Bool(client.sendCommand("RENAMENX", false, []byte(key), []byte(newKey)))
+}
+
+// Copy copies the value of src to dst, replace overwrites dst if exists.
+// Returns false if not copied.
+func (client *Client) Copy(src, dst string, replace bool) (bool, error) {
+	return client.CopyDB(src, dst, -1, replace)
+}
+
+// CopyDB copies to dst of db, the current db if db < 0
+func (client *Client) CopyDB(src, dst string, db int, replace bool) (bool, error) {
+	args := [][]byte{[]byte(src), []byte(dst)}
+	if db >= 0 {
+		args = append(args, []byte("DB"), toBytes(db))
+	}
+	if replace {
+		args = append(args, []byte("REPLACE"))
+	}
+	return Bool(client.sendCommand("COPY", false, args...))
+}
+
+// Move moves key to db, returns false if key does not exist, or exists in db
+func (client *Client) Move(key string, db int) (bool, error) {
+	return Bool(client.sendCommand("MOVE", false, []byte(key), toBytes(db)))
+}
+
+// Randomkey returns KeyDoesNotExist if the db is empty
+func (client *Client) Randomkey() (string, error) {
+	return String(client.sendCommand("RANDOMKEY", true))
+}
+
+// ObjectEncoding returns the internal encoding of the value, eg: listpack
+func (client *Client) ObjectEncoding(key string) (string, error) {
+	return String(client.sendCommand("OBJECT", true, []byte("ENCODING"), []byte(key)))
+}
+
+// ObjectIdletime returns the time since key was last accessed
+func (client *Client) ObjectIdletime(key string) (time.Duration, error) {
+	n, err := Int64(client.sendCommand("OBJECT", false, []byte("IDLETIME"), []byte(key)))
+	return time.Duration(n) * time.Second, err
+}
+
+// ObjectFreq returns the access frequency of key, with an LFU maxmemory-policy
+func (client *Client) ObjectFreq(key string) (int64, error) {
+	return Int64(client.sendCommand("OBJECT", false, []byte("FREQ"), []byte(key)))
+}
+
+// MemoryUsage returns the bytes used by key and its value, KeyDoesNotExist
+// if not exists
+func (client *Client) MemoryUsage(key string) (int64, error) {
+	return Int64(client.sendCommand("MEMORY", false, []byte("USAGE"), []byte(key)))
+}
@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+// Where of LMOVE and LMPOP
+const (
+	Left  = "LEFT"
+	Right = "RIGHT"
+)
+
+// LposArgs are options of LPOS: Rank is the nth match to return, negative
+// from the tail, MaxLen limits the elements compared, 0 for all of them
+type LposArgs struct {
+	Rank   int64
+	MaxLen int64
+}
+
+func (client *Client) Llen(key string) (int64, error) {
+	return Int64(client.sendCommand("LLEN", false, []byte(key)))
+}
+
+// Lindex returns the element at index, negative from the tail.
+// KeyDoesNotExist if out of range.
+func (client *Client) Lindex(key string, index int64) ([]byte, error) {
+	return Bytes(client.sendCommand("LINDEX", true, []byte(key), toBytes(index)))
+}
+
+func (client *Client) Lset(key string, index int64, value interface{}) error {
+	return client.simple("LSET", []byte(key), toBytes(index), toBytes(value))
+}
+
+// LinsertBefore inserts value before pivot, and returns the length of the
+// list, -1 if pivot is not found
+func (client *Client) LinsertBefore(key string, pivot, value interface{}) (int64, error) {
+	return Int64(client.sendCommand("LINSERT", false, []byte(key), []byte("BEFORE"), toBytes(pivot),
+		toBytes(value)))
+}
+
+func (client *Client) LinsertAfter(key string, pivot, value interface{}) (int64, error) {
+	return Int64(client.sendCommand("LINSERT", false, []byte(key), []byte("AFTER"), toBytes(pivot),
+		toBytes(value)))
+}
+
+// Lrem removes count occurrences of value, from the tail if count < 0, all
+// of them if 0. Returns the number removed.
+func (client *Client) Lrem(key string, count int64, value interface{}) (int64, error) {
+	return Int64(client.sendCommand("LREM", false, []byte(key), toBytes(count), toBytes(value)))
+}
+
+func lposArgs(key string, value interface{}, a *LposArgs) [][]byte {
+	args := [][]byte{[]byte(key), toBytes(value)}
+	if a != nil && a.Rank != 0 {
+		args = append(args, []byte("RANK"), toBytes(a.Rank))
+	}
+	if a != nil && a.MaxLen != 0 {
+		args = append(args, []byte("MAXLEN"), toBytes(a.MaxLen))
+	}
+	return args
+}
+
+// Lpos returns the index of value, KeyDoesNotExist if not found. a may be nil
+func (client *Client) Lpos(key string, value interface{}, a *LposArgs) (int64, error) {
+	return Int64(client.sendCommand("LPOS", false, lposArgs(key, value, a)...))
+}
+
+// LposCount returns the indexes of up to count matches, 0 for all
+func (client *Client) LposCount(key string, value interface{}, count int64, a *LposArgs) ([]int64, error) {
+	args := append(lposArgs(key, value, a), []byte("COUNT"), toBytes(count))
+	values, err := Values(client.sendCommand("LPOS", false, args...))
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]int64, len(values))
+	for i, v := range values {
+		if indexes[i], err = Int64(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+// Lpop returns KeyDoesNotExist if the list is empty
+func (client *Client) Lpop(key string) ([]byte, error) {
+	return Bytes(client.sendCommand("LPOP", true, []byte(key)))
+}
+
+// LpopCount pops up to count elements, nil if the list is empty
+func (client *Client) LpopCount(key string, count int) ([]string, error) {
+	return Strings(client.sendCommand("LPOP", true, []byte(key), toBytes(count)))
+}
+
+func (client *Client) Rpop(key string) ([]byte, error) {
+	return Bytes(client.sendCommand("RPOP", true, []byte(key)))
+}
+
+func (client *Client) RpopCount(key string, count int) ([]string, error) {
+	return Strings(client.sendCommand("RPOP", true, []byte(key), toBytes(count)))
+}
+
+// Lpushx pushes only if the list exists, returns the length of the list
+func (client *Client) Lpushx(key string, values ...interface{}) (int64, error) {
+	return client.listPush("LPUSHX", key, values)
+}
+
+func (client *Client) Rpushx(key string, values ...interface{}) (int64, error) {
+	return client.listPush("RPUSHX", key, values)
+}
+
+// Lmove pops an element from the Left or Right of src, pushes it to the
+// Left or Right of dst, and returns it. KeyDoesNotExist if src is empty.
+func (client *Client) Lmove(src, dst, srcWhere, dstWhere string) ([]byte, error) {
+	return Bytes(client.sendCommand("LMOVE", true, []byte(src), []byte(dst), []byte(srcWhere),
+		[]byte(dstWhere)))
+}
+
+// Blmove is the blocking Lmove, like Brpop, it returns nil on timeout
+func (client *Client) Blmove(src, dst, srcWhere, dstWhere string, seconds int) ([]byte, error) {
+	return client.blockMove("BLMOVE", seconds, []byte(src), []byte(dst), []byte(srcWhere), []byte(dstWhere))
+}
+
+func (client *Client) Rpoplpush(src, dst string) ([]byte, error) {
+	return Bytes(client.sendCommand("RPOPLPUSH", true, []byte(src), []byte(dst)))
+}
+
+// Brpoplpush returns nil on timeout
+func (client *Client) Brpoplpush(src, dst string, seconds int) ([]byte, error) {
+	return client.blockMove("BRPOPLPUSH", seconds, []byte(src), []byte(dst))
+}
+
+func (client *Client) blockMove(cmd string, seconds int, args ...[]byte) ([]byte, error) {
+	args = append(args, toBytes(seconds))
+	value, err := client.sendBlocking(blockFor(seconds), cmd, true, args...)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return Bytes(value, nil)
+}
+
+// Lmpop pops up to count elements from the Left or Right of the first non
+// empty list of keys, returns the key and the elements. KeyDoesNotExist if
+// all are empty.
+func (client *Client) Lmpop(where string, count int, keys ...string) (string, []string, error) {
+	key, values, err := client.lmpop(0, "LMPOP", where, count, keys, nil)
+	if err == nil && values == nil {
+		err = KeyDoesNotExist
+	}
+	return key, values, err
+}
+
+// Blmpop is the blocking Lmpop, returns "" and nil on timeout
+func (client *Client) Blmpop(seconds int, where string, count int, keys ...string) (string, []string, error) {
+	return client.lmpop(blockFor(seconds), "BLMPOP", where, count, keys, toBytes(seconds))
+}
+
+func (client *Client) lmpop(block time.Duration, cmd, where string, count int, keys []string,
+	timeout []byte) (string, []string, error) {
+	var args [][]byte
+	if timeout != nil {
+		args = append(args, timeout)
+	}
+	args = append(args, toBytes(len(keys)))
+	args = append(args, stringArgs(keys)...)
+	args = append(args, []byte(where), []byte("COUNT"), toBytes(count))
+	values, err := Values(client.sendBlocking(block, cmd, true, args...))
+	if err != nil || values == nil {
+		return "", nil, err
+	}
+	if len(values) != 2 {
+		return "", nil, fmt.Errorf("Unexpected reply of %s %v", cmd, values)
+	}
+	key, _ := String(values[0], nil)
+	elements, err := Strings(values[1], nil)
+	return key, elements, err
+}
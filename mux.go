@@ -0,0 +1,284 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// blocking commands are never multiplexed, they would block the others
+var blocking = map[string]bool{
+	"BLPOP": true, "BRPOP": true, "BRPOPLPUSH": true, "BLMOVE": true, "BLMPOP": true,
+	"BZPOPMIN": true, "BZPOPMAX": true, "BZMPOP": true, "XREAD": true, "XREADGROUP": true,
+	"WAIT": true, "WAITAOF": true,
+}
+
+type muxCall struct {
+	cmd  string
+	args [][]byte
+	val  interface{}
+	err  error
+	done chan struct{}
+}
+
+// mux multiplexes commands of concurrent callers on a few shared
+// connections: commands queued while a write is in progress are coalesced
+// into the next write, and replies are dispatched back in order.
+type mux struct {
+	dial    func(ctx context.Context) (*RedisConn, error)
+	next    atomic.Uint32
+	mu      sync.Mutex // protect fields below
+	conns   []*muxConn // nil or broken ones are dialed again
+	dialing []*muxDial // of conns, nil if not dialing
+}
+
+// muxDial is the dial of a connection, waited by callers of the slot
+type muxDial struct {
+	done chan struct{} // closed once dialed, or failed
+	mc   *muxConn
+	err  error
+}
+
+func newMux(n int, dial func(ctx context.Context) (*RedisConn, error)) *mux {
+	return &mux{dial: dial, conns: make([]*muxConn, n), dialing: make([]*muxDial, n)}
+}
+
+// conn returns a shared connection, round robin. A connection is dialed
+// without holding m.mu, callers of the slot wait for it until their ctx done.
+func (m *mux) conn(ctx context.Context) (*muxConn, error) {
+	m.mu.Lock()
+	if m.conns == nil {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	i := int(m.next.Add(1)) % len(m.conns)
+	if mc := m.conns[i]; mc != nil && !mc.isDead() {
+		m.mu.Unlock()
+		return mc, nil
+	}
+	d := m.dialing[i]
+	if d == nil {
+		d = &muxDial{done: make(chan struct{})}
+		m.dialing[i] = d
+		go m.dialSlot(context.WithoutCancel(ctx), i, d)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-d.done:
+		return d.mc, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dialSlot dials the ith connection, not canceled with the caller, as
+// others may be waiting for it. DialTimeout limits it.
+func (m *mux) dialSlot(ctx context.Context, i int, d *muxDial) {
+	c, err := m.dial(ctx)
+	m.mu.Lock()
+	if err == nil && m.conns == nil {
+		c.conn.Close()
+		err = ErrClosed
+	}
+	if err == nil {
+		d.mc = &muxConn{c: c, wake: make(chan struct{}, 1), readable: make(chan struct{}, 1)}
+		go d.mc.writeLoop()
+		go d.mc.readLoop()
+		m.conns[i] = d.mc
+	}
+	d.err = err
+	m.dialing[i] = nil
+	m.mu.Unlock()
+	close(d.done)
+}
+
+// open returns the number of connections open
+func (m *mux) open() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, mc := range m.conns {
+		if mc != nil && !mc.isDead() {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *mux) do(ctx context.Context, cmd string, args [][]byte) (interface{}, error) {
+	mc, err := m.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	call := &muxCall{cmd: cmd, args: args, done: make(chan struct{})}
+	if err := mc.enqueue(call); err != nil {
+		return nil, err
+	}
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done(): // the reply is read, and dropped
+		return nil, ctx.Err()
+	}
+}
+
+func (m *mux) close() {
+	m.mu.Lock()
+	conns := m.conns
+	m.conns = nil
+	m.mu.Unlock()
+	for _, mc := range conns {
+		if mc != nil {
+			mc.fail(ErrClosed)
+		}
+	}
+}
+
+type muxConn struct {
+	c *RedisConn
+
+	mu       sync.Mutex // protect fields below
+	pending  []*muxCall // to be written
+	inflight []*muxCall // written, waiting for replies
+	err      error      // not nil once broken
+
+	wake     chan struct{} // the writer, pending is not empty
+	readable chan struct{} // the reader, inflight is not empty
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (mc *muxConn) isDead() bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.err != nil
+}
+
+func (mc *muxConn) enqueue(call *muxCall) error {
+	mc.mu.Lock()
+	if mc.err != nil {
+		mc.mu.Unlock()
+		return mc.err
+	}
+	mc.pending = append(mc.pending, call)
+	mc.mu.Unlock()
+	signal(mc.wake)
+	return nil
+}
+
+// fail closes the connection, and fails the calls not replied
+func (mc *muxConn) fail(err error) {
+	mc.mu.Lock()
+	if mc.err != nil {
+		mc.mu.Unlock()
+		return
+	}
+	mc.err = err
+	calls := append(mc.inflight, mc.pending...)
+	mc.inflight, mc.pending = nil, nil
+	mc.mu.Unlock()
+
+	mc.c.conn.Close()
+	signal(mc.wake) // let them exit
+	signal(mc.readable)
+	for _, call := range calls {
+		call.err = err
+		close(call.done)
+	}
+}
+
+func (mc *muxConn) writeLoop() {
+	var batch []*muxCall
+	for range mc.wake {
+		mc.mu.Lock()
+		if mc.err != nil {
+			mc.mu.Unlock()
+			return
+		}
+		batch, mc.pending = mc.pending, batch[:0]
+		// queued for reading before written, replies may come at once
+		mc.inflight = append(mc.inflight, batch...)
+		mc.mu.Unlock()
+		if len(batch) == 0 {
+			continue
+		}
+		signal(mc.readable)
+
+		wbuf := mc.c.wbuf
+		wbuf.pos = 0
+		for i, call := range batch {
+			wbuf.appendRequest(call.cmd, call.args)
+			batch[i] = nil
+		}
+		if err := mc.c.flush(); err != nil {
+			mc.fail(err)
+			return
+		}
+	}
+}
+
+func (mc *muxConn) readLoop() {
+	for range mc.readable {
+		for {
+			mc.mu.Lock()
+			if mc.err != nil {
+				mc.mu.Unlock()
+				return
+			}
+			if len(mc.inflight) == 0 {
+				mc.mu.Unlock()
+				break
+			}
+			call := mc.inflight[0]
+			mc.mu.Unlock()
+
+			mc.c.rbuf.compact()
+			v, err := mc.c.readReply(0)
+			if _, ok := err.(RedisError); !ok && err != nil {
+				mc.fail(err)
+				return
+			}
+
+			mc.mu.Lock()
+			if mc.err != nil { // failed, and the call as well
+				mc.mu.Unlock()
+				return
+			}
+			mc.inflight[0] = nil
+			mc.inflight = mc.inflight[1:]
+			mc.mu.Unlock()
+			// the read buffer is reused, copy the reply before handing it out
+			call.val, call.err = copyReply(v), err
+			close(call.done)
+		}
+	}
+}
+
+// copyReply deep copies the []byte of a reply
+func copyReply(v interface{}) interface{} {
+	switch r := v.(type) {
+	case []byte:
+		return copyBytes(r)
+	case []interface{}:
+		rets := make([]interface{}, len(r))
+		for i, e := range r {
+			rets[i] = copyReply(e)
+		}
+		return rets
+	case Push:
+		return Push(copyReply([]interface{}(r)).([]interface{}))
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(r))
+		for k, e := range r {
+			m[k] = copyReply(e)
+		}
+		return m
+	}
+	return v
+}
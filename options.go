@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+type Options struct {
+	Network string // tcp or unix, tcp if empty
+	Addr    string // host:port, or the path of a unix socket
+	Db      int
+	MaxCon  int // max idle connections kept in the pool, DefaultMaxCon if 0
+
+	// Connections are TLS if TLSConfig is not nil. The server name is taken
+	// from Addr if TLSConfig.ServerName is empty.
+	TLSConfig *tls.Config
+	// Dialer opens connections instead, for any other transport. DialTimeout
+	// is applied to ctx, TLSConfig is ignored.
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// 2 or 3, RESP3 is negotiated by HELLO 3 on connect, which needs redis 6.
+	// Replies are then typed, eg: maps, doubles and booleans, see Client.Do
+	Protocol int
+	// Called with RESP3 push messages received along with replies, eg: the
+	// invalidation messages of CLIENT TRACKING
+	OnPush func(Push)
+
+	// Sent on connect: AUTH [Username] Password, CLIENT SETNAME ClientName,
+	// SELECT Db, then OnConnect is called. An error of any aborts the dial.
+	Username   string // ACL user of redis 6, "default" if empty
+	Password   string
+	ClientName string
+	OnConnect  func(c *RedisConn) error
+
+	// Commands of concurrent callers are pipelined on Multiplex shared
+	// connections, 0 disables it. Blocking and stateful commands, and
+	// Pipeline, still use connections of the pool.
+	Multiplex int
+
+	// Max connections in use at a time, 0 means no limit. When exhausted,
+	// callers wait up to PoolTimeout for one to be returned, 0 means waiting
+	// as long as the context of the command allows.
+	MaxActive   int
+	PoolTimeout time.Duration
+	// Connections dialed beforehand and kept idle, no more than MaxCon
+	MinIdle int
+	// Idle connections are closed after IdleTimeout, and all of them after
+	// MaxConnLifetime, checked every IdleCheckFrequency (1 minute if 0)
+	IdleTimeout        time.Duration
+	MaxConnLifetime    time.Duration
+	IdleCheckFrequency time.Duration
+
+	// Timeouts of a single dial, read or write, 0 means no timeout.
+	// Blocking commands, like BRPOP, extend ReadTimeout by their own timeout.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// Read-only commands are sent again, up to MaxRetries
+	// times, if the connection breaks. Retries back off exponentially from
+	// MinRetryBackoff (8ms if 0) to MaxRetryBackoff (512ms if 0).
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+
+	// Client.Watch runs a transaction again, up to MaxTxRetries (10 if 0)
+	// times, when a watched key is modified
+	MaxTxRetries int
+}
+
+func (opts *Options) init() {
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	if opts.MaxCon == 0 {
+		opts.MaxCon = DefaultMaxCon
+	}
+	if opts.Protocol == 0 {
+		opts.Protocol = 2
+	}
+	if opts.MinIdle > opts.MaxCon {
+		opts.MinIdle = opts.MaxCon
+	}
+	if opts.IdleCheckFrequency == 0 &&
+		(opts.IdleTimeout > 0 || opts.MaxConnLifetime > 0 || opts.MinIdle > 0) {
+		opts.IdleCheckFrequency = time.Minute
+	}
+	if opts.MinRetryBackoff == 0 {
+		opts.MinRetryBackoff = 8 * time.Millisecond
+	}
+	if opts.MaxRetryBackoff == 0 {
+		opts.MaxRetryBackoff = 512 * time.Millisecond
+	}
+	if opts.MaxTxRetries == 0 {
+		opts.MaxTxRetries = 10
+	}
+}
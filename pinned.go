@@ -0,0 +1,105 @@
+package redis
+
+import "sync"
+
+// commands changing the state of a connection, a connection of the pool is
+// discarded after one of them, except a pinned one of Conn
+var stateful = map[string]bool{
+	"SELECT": true, "WATCH": true, "MULTI": true, "CLIENT": true, "HELLO": true,
+	"AUTH": true, "READONLY": true, "READWRITE": true, "RESET": true, "MONITOR": true,
+	"SUBSCRIBE": true, "PSUBSCRIBE": true, "SSUBSCRIBE": true,
+}
+
+type pinnedConn struct {
+	c      *RedisConn // nil after Close
+	broken bool
+}
+
+// Conn is a dedicated connection for a sequence of stateful commands, eg:
+// SELECT, WATCH or CLIENT. It has all the commands of Client, and is not
+// safe for concurrent use.
+type Conn struct {
+	*Client
+	borrowed bool // the connection of another Conn, not closed by Close
+}
+
+// Conn takes a connection out of the pool, until Conn.Close. Of a Conn, eg:
+// by Tx or Watch, it shares the connection, which is closed by the owner.
+func (client *Client) Conn() (*Conn, error) {
+	if client.pinned != nil {
+		if _, err := client.getCon(); err != nil {
+			return nil, err
+		}
+		return &Conn{Client: client, borrowed: true}, nil
+	}
+	c, err := client.getCon()
+	if err != nil {
+		return nil, err
+	}
+	pinned := *client
+	pinned.pinned = &pinnedConn{c: c}
+	return &Conn{Client: &pinned}, nil
+}
+
+// Close returns the connection to the pool, or closes it if its state is
+// changed by a command
+func (conn *Conn) Close() error {
+	p := conn.pinned
+	if conn.borrowed {
+		return nil
+	}
+	if p.c == nil {
+		return ErrClosed
+	}
+	if p.broken || p.c.dirty {
+		conn.pool.remove(p.c)
+	} else {
+		conn.pool.put(p.c)
+	}
+	p.c = nil
+	return nil
+}
+
+type dbPools struct {
+	mu     sync.Mutex // protect fields below
+	pools  map[int]*connPool
+	closed bool
+}
+
+func (dbs *dbPools) close() error {
+	dbs.mu.Lock()
+	defer dbs.mu.Unlock()
+	if dbs.closed {
+		return ErrClosed
+	}
+	dbs.closed = true
+	for _, p := range dbs.pools {
+		p.close()
+	}
+	return nil
+}
+
+// WithDB returns a client sharing the options of client, whose pooled
+// connections use db. Clients of the same db share the same pool.
+func (client *Client) WithDB(db int) *Client {
+	if client.pinned != nil {
+		panic("WithDB of a Conn, use Select instead")
+	}
+	dbs := client.dbs
+	dbs.mu.Lock()
+	defer dbs.mu.Unlock()
+	c := *client
+	if dbs.closed { // the pool is closed as well, commands fail with ErrClosed
+		return &c
+	}
+	p, ok := dbs.pools[db]
+	if !ok {
+		dialer := *client // the pool dials with the options of it
+		dialer.ctx = nil
+		dialer.Db, dialer.opts.Db = db, db
+		p = newConnPool(&dialer.opts, dialer.openConn)
+		dbs.pools[db] = p
+	}
+	c.Db, c.opts, c.pool = db, *p.opts, p
+	return &c
+}
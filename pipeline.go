@@ -1,71 +1,210 @@
 package redis
 
 import (
+	"errors"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
+var errNotExecuted = errors.New("Redis Error: Pipeline is not executed")
+
 type Pipeline struct {
-	client *Client
-	con    *RedisConn
-	count  int
+	client   *Client
+	con      *RedisConn // nil after Execute
+	replies  []*Reply
+	stateful bool            // a queued command changes the state of the connection
+	scripts  map[string]bool // SHA1 of scripts loaded by Script.Pipe
+}
+
+// Reply is the reply of a pipelined command, available after Execute
+type Reply struct {
+	val interface{}
+	err error
+}
+
+// Result returns the reply, as of Client.Do
+func (r *Reply) Result() (interface{}, error) { return r.val, r.err }
+
+func (r *Reply) Err() error                            { return r.err }
+func (r *Reply) Int64() (int64, error)                 { return Int64(r.val, r.err) }
+func (r *Reply) Float64() (float64, error)             { return Float64(r.val, r.err) }
+func (r *Reply) Bool() (bool, error)                   { return Bool(r.val, r.err) }
+func (r *Reply) Bytes() ([]byte, error)                { return Bytes(r.val, r.err) }
+func (r *Reply) String() (string, error)               { return String(r.val, r.err) }
+func (r *Reply) Values() ([]interface{}, error)        { return Values(r.val, r.err) }
+func (r *Reply) Strings() ([]string, error)            { return Strings(r.val, r.err) }
+func (r *Reply) StringMap() (map[string]string, error) { return StringMap(r.val, r.err) }
+
+// queue queues a command, its reply is filled by Execute
+func (pipe *Pipeline) queue(cmd string, args ...[]byte) *Reply {
+	r := &Reply{err: errNotExecuted}
+	if pipe.con == nil {
+		r.err = ErrClosed
+		return r
+	}
+	pipe.con.wbuf.appendRequest(cmd, args)
+	pipe.replies = append(pipe.replies, r)
+	pipe.stateful = pipe.stateful || stateful[cmd]
+	return r
+}
+
+// Do queues any command, args are encoded as by Client.Do
+func (pipe *Pipeline) Do(cmd string, args ...interface{}) *Reply {
+	return pipe.queueArgs(strings.ToUpper(cmd), nil, args)
+}
+
+// queueArgs queues cmd with prefix and the args encoded, the reply of which
+// is the error if any of args can't be encoded, and the command not sent
+func (pipe *Pipeline) queueArgs(cmd string, prefix [][]byte, args []interface{}) *Reply {
+	bs, err := toArgs(args)
+	if err != nil {
+		return &Reply{err: err}
+	}
+	return pipe.queue(cmd, append(prefix, bs...)...)
+}
+
+func (pipe *Pipeline) Hincrby(key, field string, inc int) *Reply {
+	return pipe.queue("HINCRBY", []byte(key), []byte(field), []byte(strconv.Itoa(inc)))
+}
+
+// Expire queues EXPIRE, or PEXPIRE if ttl is not whole seconds, as of
+// Client.Expire
+func (pipe *Pipeline) Expire(key string, ttl time.Duration) *Reply {
+	if ttl%time.Second == 0 {
+		return pipe.queue("EXPIRE", []byte(key), toBytes(int64(ttl/time.Second)))
+	}
+	ms := ttl.Milliseconds()
+	if ttl%time.Millisecond > 0 {
+		ms++
+	}
+	return pipe.queue("PEXPIRE", []byte(key), toBytes(ms))
+}
+
+func (pipe *Pipeline) Ping() *Reply {
+	return pipe.queue("PING")
 }
 
-func (pipe *Pipeline) Hincrby(key, field string, inc int) {
-	pipe.count += 1
-	wbuf := pipe.con.wbuf
-	wbuf.buffer[wbuf.pos] = '*'
-	wbuf.pos += 1
-	wbuf.writeInt(4)
-	wbuf.writeBytes([]byte("HINCRBY"))
+func (pipe *Pipeline) Get(key string) *Reply {
+	return pipe.queue("GET", []byte(key))
+}
+
+func (pipe *Pipeline) MGet(keys ...string) *Reply {
+	ks := make([][]byte, len(keys))
+	for i, v := range keys {
+		ks[i] = []byte(v)
+	}
+	return pipe.queue("MGET", ks...)
+}
 
-	wbuf.writeBytes([]byte(key))
-	wbuf.writeBytes([]byte(field))
-	wbuf.writeBytes([]byte(strconv.Itoa(inc)))
+func (pipe *Pipeline) Set(key string, data interface{}) *Reply {
+	return pipe.queue("SET", []byte(key), toBytes(data))
 }
 
-func (pipe *Pipeline) Expire(key string, seconds int) {
-	pipe.count += 1
-	wbuf := pipe.con.wbuf
-	wbuf.buffer[wbuf.pos] = '*'
-	wbuf.pos += 1
-	wbuf.writeInt(3)
-	wbuf.writeBytes([]byte("EXPIRE"))
-	wbuf.writeBytes([]byte(key))
-	wbuf.writeBytes([]byte(strconv.Itoa(seconds)))
+func (pipe *Pipeline) Setex(key string, seconds int, data interface{}) *Reply {
+	return pipe.queue("SETEX", []byte(key), []byte(strconv.Itoa(seconds)), toBytes(data))
 }
 
-func (pipe *Pipeline) Ping() {
-	pipe.count += 1
-	wbuf := pipe.con.wbuf
-	wbuf.buffer[wbuf.pos] = '*'
-	wbuf.pos += 1
-	wbuf.writeInt(1)
-	wbuf.writeBytes([]byte("PING"))
+func (pipe *Pipeline) Setnx(key string, data interface{}) *Reply {
+	return pipe.queue("SETNX", []byte(key), toBytes(data))
 }
 
+func (pipe *Pipeline) Del(keys ...string) *Reply {
+	return pipe.queue("DEL", stringArgs(keys)...)
+}
+
+func (pipe *Pipeline) Lpush(key string, values ...interface{}) *Reply {
+	return pipe.queueArgs("LPUSH", [][]byte{[]byte(key)}, values)
+}
+
+func (pipe *Pipeline) Rpush(key string, values ...interface{}) *Reply {
+	return pipe.queueArgs("RPUSH", [][]byte{[]byte(key)}, values)
+}
+
+func (pipe *Pipeline) Lrange(key string, start, stop int) *Reply {
+	return pipe.queue("LRANGE", []byte(key), toBytes(start), toBytes(stop))
+}
+
+func (pipe *Pipeline) Ltrim(key string, start, end int) *Reply {
+	return pipe.queue("LTRIM", []byte(key), toBytes(start), toBytes(end))
+}
+
+func (pipe *Pipeline) Sadd(key string, members ...interface{}) *Reply {
+	return pipe.queueArgs("SADD", [][]byte{[]byte(key)}, members)
+}
+
+func (pipe *Pipeline) Smembers(key string) *Reply {
+	return pipe.queue("SMEMBERS", []byte(key))
+}
+
+func (pipe *Pipeline) Hgetall(key string) *Reply {
+	return pipe.queue("HGETALL", []byte(key))
+}
+
+func (pipe *Pipeline) Hmset(key string, mapping map[string]interface{}) *Reply {
+	args := [][]byte{[]byte(key)}
+	if err := mappingToArgs(reflect.ValueOf(mapping), &args); err != nil {
+		return &Reply{err: err}
+	}
+	return pipe.queue("HMSET", args...)
+}
+
+// Discard releases the connection without sending the queued commands, whose
+// replies are ErrClosed. It does nothing after Execute, it may be deferred.
+func (pipe *Pipeline) Discard() {
+	c := pipe.con
+	if c == nil {
+		return
+	}
+	pipe.con = nil
+	c.wbuf.pos = 0
+	for _, r := range pipe.replies {
+		r.err = ErrClosed
+	}
+	pipe.client.returnCon(c)
+}
+
+// Execute sends the queued commands, and fills their replies. A server error
+// is the error of the reply of a command, the error returned is of the
+// connection, eg: a timeout, in which case replies unread have it as well.
 func (pipe *Pipeline) Execute() error {
 	c := pipe.con
-	pos := 0
-	for pos < c.wbuf.pos {
-		n, err := c.conn.Write(c.wbuf.buffer[pos:c.wbuf.pos])
-		if err != nil {
-			if c.conn != nil {
-				c.conn.Close()
+	if c == nil {
+		return ErrClosed
+	}
+	pipe.con = nil
+	ctx := pipe.client.Context()
+	stop := c.watch(ctx)
+	old := c.rbuf // using a new buffer, the replies are valid after
+	c.rbuf = &ByteBuffer{buffer: make([]byte, BufferSize)}
+
+	err := c.flush()
+	for _, r := range pipe.replies {
+		if err == nil {
+			r.val, r.err = c.readReply(0)
+			if _, ok := r.err.(RedisError); !ok && r.err != nil {
+				err = r.err
 			}
-			return err
+		} else {
+			r.val, r.err = nil, err
 		}
-		pos += n
 	}
-	var e error
-	for i := 0; i < pipe.count; i++ {
-		if _, err := c.readResponse(); err != nil {
-			e = err
+	c.rbuf = old // before released, it may be used by others then
+
+	if stop() || (err != nil && ctxErr(ctx) != nil) {
+		if err != nil {
+			err = ctxErr(ctx)
 		}
+		pipe.client.removeCon(c)
+	} else if err != nil {
+		pipe.client.removeCon(c)
+	} else if !pipe.stateful {
+		pipe.client.returnCon(c)
+	} else if pipe.client.pinned != nil {
+		c.dirty = true
+	} else {
+		pipe.client.removeCon(c)
 	}
-	if e == nil {
-		pipe.client.returnCon(pipe.con)
-	} else if c.conn != nil {
-		c.conn.Close()
-	}
-	return e
+	return err
 }
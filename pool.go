@@ -0,0 +1,257 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrPoolTimeout = errors.New("Redis Error: Connection pool timeout")
+	ErrClosed      = errors.New("Redis Error: Client is closed")
+	ErrBadConn     = errors.New("Redis Error: Connection is broken")
+)
+
+type PoolStats struct {
+	Hits     uint64 // idle connection reused
+	Misses   uint64 // no idle connection, a new one is dialed
+	Waits    uint64 // waited for a connection, the pool is exhausted
+	Timeouts uint64 // waited too long for a connection
+
+	TotalConns int // open connections, idle, in use, or multiplexed
+	IdleConns  int
+}
+
+type connPool struct {
+	opts *Options
+	dial func(ctx context.Context) (*RedisConn, error)
+
+	sem chan struct{} // a token per connection in use, nil if MaxActive is 0
+
+	mu     sync.Mutex //  protect fields below
+	idle   []*RedisConn
+	open   int
+	closed bool
+
+	hits, misses, waits, timeouts atomic.Uint64
+
+	done chan struct{} // stop the reaper
+	mux  *mux          // nil if Options.Multiplex is 0
+}
+
+func newConnPool(opts *Options, dial func(ctx context.Context) (*RedisConn, error)) *connPool {
+	p := &connPool{opts: opts, dial: dial, done: make(chan struct{})}
+	if opts.MaxActive > 0 {
+		p.sem = make(chan struct{}, opts.MaxActive)
+	}
+	if opts.Multiplex > 0 {
+		p.mux = newMux(opts.Multiplex, dial)
+	}
+	p.fillIdle()
+	if opts.IdleCheckFrequency > 0 {
+		go p.reaper(opts.IdleCheckFrequency)
+	}
+	return p
+}
+
+func (p *connPool) acquire(ctx context.Context) error {
+	if p.sem == nil {
+		return nil
+	}
+	select {
+	case p.sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	p.waits.Add(1)
+	var timeout <-chan time.Time
+	if p.opts.PoolTimeout > 0 {
+		timer := time.NewTimer(p.opts.PoolTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case p.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout:
+		p.timeouts.Add(1)
+		return ErrPoolTimeout
+	}
+}
+
+func (p *connPool) release() {
+	if p.sem != nil {
+		<-p.sem
+	}
+}
+
+func (p *connPool) get(ctx context.Context) (*RedisConn, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.release()
+		return nil, ErrClosed
+	}
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.isStale(c, now) {
+			p.open--
+			c.conn.Close()
+			continue
+		}
+		p.mu.Unlock()
+		p.hits.Add(1)
+		return c, nil
+	}
+	p.open++ // reserve it, so that TotalConns never underestimates
+	p.mu.Unlock()
+
+	p.misses.Add(1)
+	c, err := p.dial(ctx)
+	if err != nil {
+		p.mu.Lock()
+		p.open--
+		p.mu.Unlock()
+		p.release()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *connPool) put(c *RedisConn) {
+	c.usedAt = time.Now()
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.opts.MaxCon || p.isStale(c, c.usedAt) {
+		p.open--
+		c.conn.Close()
+	} else {
+		p.idle = append(p.idle, c)
+	}
+	p.mu.Unlock()
+	p.release()
+}
+
+// remove closes a connection got from the pool, which is broken or can not
+// be reused
+func (p *connPool) remove(c *RedisConn) {
+	c.conn.Close()
+	p.mu.Lock()
+	p.open--
+	p.mu.Unlock()
+	p.release()
+}
+
+func (p *connPool) isStale(c *RedisConn, now time.Time) bool {
+	if p.opts.IdleTimeout > 0 && now.Sub(c.usedAt) >= p.opts.IdleTimeout {
+		return true
+	}
+	return p.opts.MaxConnLifetime > 0 && now.Sub(c.createdAt) >= p.opts.MaxConnLifetime
+}
+
+// fillIdle dials connections until MinIdle of them are idle
+func (p *connPool) fillIdle() {
+	for {
+		p.mu.Lock()
+		if p.closed || len(p.idle) >= p.opts.MinIdle {
+			p.mu.Unlock()
+			return
+		}
+		p.open++
+		p.mu.Unlock()
+
+		c, err := p.dial(context.Background())
+		p.mu.Lock()
+		if err != nil || p.closed {
+			p.open--
+			p.mu.Unlock()
+			if c != nil {
+				c.conn.Close()
+			}
+			return
+		}
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+	}
+}
+
+// reapStale closes stale idle connections
+func (p *connPool) reapStale() {
+	now := time.Now()
+	p.mu.Lock()
+	idle := p.idle[:0]
+	for _, c := range p.idle {
+		if p.isStale(c, now) {
+			p.open--
+			c.conn.Close()
+		} else {
+			idle = append(idle, c)
+		}
+	}
+	for i := len(idle); i < len(p.idle); i++ {
+		p.idle[i] = nil
+	}
+	p.idle = idle
+	p.mu.Unlock()
+}
+
+func (p *connPool) reaper(frequency time.Duration) {
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.reapStale()
+			p.fillIdle()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *connPool) stats() PoolStats {
+	p.mu.Lock()
+	total, idle := p.open, len(p.idle)
+	p.mu.Unlock()
+	if p.mux != nil {
+		total += p.mux.open()
+	}
+	return PoolStats{
+		Hits:       p.hits.Load(),
+		Misses:     p.misses.Load(),
+		Waits:      p.waits.Load(),
+		Timeouts:   p.timeouts.Load(),
+		TotalConns: total,
+		IdleConns:  idle,
+	}
+}
+
+func (p *connPool) close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	for _, c := range p.idle {
+		p.open--
+		c.conn.Close()
+	}
+	p.idle = nil
+	p.mu.Unlock()
+	close(p.done)
+	if p.mux != nil {
+		p.mux.close()
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a message published to a subscribed channel
+type Message struct {
+	Kind    string // message, pmessage or smessage
+	Channel string
+	Pattern string // the matched pattern of pmessage
+	Payload []byte
+}
+
+type PubSubOptions struct {
+	// Capacity of the channel of messages, 100 if 0
+	Buffer int
+	// When the channel is full, a message is dropped after waiting
+	// SendTimeout. 0 waits as long as it takes: the connection is not read
+	// meanwhile, messages are buffered by redis, up to
+	// client-output-buffer-limit, and then the connection is closed. While
+	// an (un)subscribe waits for its confirmation, the connection is read,
+	// and messages are held until confirmed.
+	SendTimeout time.Duration
+	// PING is sent every PingInterval (30s if 0). Nothing received in twice
+	// the interval means the connection is broken, it's dialed again.
+	PingInterval time.Duration
+}
+
+// commands subscribing, by the unsubscribing ones
+var unsubscribes = map[string]string{
+	"UNSUBSCRIBE": "SUBSCRIBE", "PUNSUBSCRIBE": "PSUBSCRIBE", "SUNSUBSCRIBE": "SSUBSCRIBE",
+}
+
+// PubSub receives messages of subscribed channels on a dedicated
+// connection, it's safe for concurrent use. Channels are subscribed again
+// after a reconnect, messages published in between are lost.
+type PubSub struct {
+	client  *Client
+	opts    PubSubOptions
+	ch      chan *Message
+	pending []*Message // of the reader, not delivered while waiting for a confirmation
+	dropped atomic.Uint64
+	done    chan struct{} // closed by Close
+	wait    chan struct{} // an (un)subscribe starts waiting, wakes deliver
+
+	cmdMu sync.Mutex // one (un)subscribe waiting for confirmation at a time
+
+	mu      sync.Mutex // protect fields below
+	c       *RedisConn // nil while reconnecting
+	subs    map[string]map[string]bool
+	waiting map[string]bool // eg: "subscribe news", not yet confirmed
+	acked   chan struct{}   // closed once all confirmed, or on error
+	ackErr  error
+	closed  bool
+}
+
+// PubSub dials a connection for subscribing, opts may be nil. Close it
+// after use, it's not closed by Client.Close.
+func (client *Client) PubSub(opts *PubSubOptions) (*PubSub, error) {
+	ps := &PubSub{client: client, done: make(chan struct{}), wait: make(chan struct{}, 1),
+		subs: map[string]map[string]bool{}}
+	if opts != nil {
+		ps.opts = *opts
+	}
+	if ps.opts.Buffer == 0 {
+		ps.opts.Buffer = 100
+	}
+	if ps.opts.PingInterval == 0 {
+		ps.opts.PingInterval = 30 * time.Second
+	}
+	c, err := client.openConn(client.Context())
+	if err != nil {
+		return nil, err
+	}
+	ps.c = c
+	ps.ch = make(chan *Message, ps.opts.Buffer)
+	go ps.run(c)
+	go ps.ping()
+	return ps, nil
+}
+
+// Subscribe returns a PubSub subscribing channels
+func (client *Client) Subscribe(channels ...string) (*PubSub, error) {
+	return client.newPubSub("SUBSCRIBE", channels)
+}
+
+// Psubscribe returns a PubSub subscribing patterns, eg: news.*
+func (client *Client) Psubscribe(patterns ...string) (*PubSub, error) {
+	return client.newPubSub("PSUBSCRIBE", patterns)
+}
+
+func (client *Client) newPubSub(cmd string, names []string) (*PubSub, error) {
+	ps, err := client.PubSub(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.do(cmd, names); err != nil {
+		ps.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Publish returns the number of clients receiving the message
+func (client *Client) Publish(channel string, message interface{}) (int64, error) {
+	return Int64(client.sendCommand("PUBLISH", false, []byte(channel), toBytes(message)))
+}
+
+// Spublish publishes to a shard channel of redis 7
+func (client *Client) Spublish(channel string, message interface{}) (int64, error) {
+	return Int64(client.sendCommand("SPUBLISH", false, []byte(channel), toBytes(message)))
+}
+
+// Channel returns the channel of messages, closed after Close
+func (ps *PubSub) Channel() <-chan *Message {
+	return ps.ch
+}
+
+// Dropped returns the number of messages dropped, see SendTimeout
+func (ps *PubSub) Dropped() uint64 {
+	return ps.dropped.Load()
+}
+
+// Subscribe returns once the channels are subscribed
+func (ps *PubSub) Subscribe(channels ...string) error {
+	return ps.do("SUBSCRIBE", channels)
+}
+
+func (ps *PubSub) Psubscribe(patterns ...string) error {
+	return ps.do("PSUBSCRIBE", patterns)
+}
+
+// Ssubscribe subscribes shard channels of redis 7
+func (ps *PubSub) Ssubscribe(channels ...string) error {
+	return ps.do("SSUBSCRIBE", channels)
+}
+
+// Unsubscribe unsubscribes the channels, or all of them if none
+func (ps *PubSub) Unsubscribe(channels ...string) error {
+	return ps.do("UNSUBSCRIBE", channels)
+}
+
+func (ps *PubSub) Punsubscribe(patterns ...string) error {
+	return ps.do("PUNSUBSCRIBE", patterns)
+}
+
+func (ps *PubSub) Sunsubscribe(channels ...string) error {
+	return ps.do("SUNSUBSCRIBE", channels)
+}
+
+// do sends a (un)subscribe, and waits for the confirmations
+func (ps *PubSub) do(cmd string, names []string) error {
+	ps.cmdMu.Lock()
+	defer ps.cmdMu.Unlock()
+
+	ps.mu.Lock()
+	if ps.closed {
+		ps.mu.Unlock()
+		return ErrClosed
+	}
+	if sub, ok := unsubscribes[cmd]; ok {
+		set := ps.subs[sub]
+		if len(names) == 0 {
+			for name := range set {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			delete(set, name)
+		}
+	} else {
+		if ps.subs[cmd] == nil {
+			ps.subs[cmd] = map[string]bool{}
+		}
+		for _, name := range names {
+			ps.subs[cmd][name] = true
+		}
+	}
+	if len(names) == 0 {
+		ps.mu.Unlock()
+		return nil
+	}
+
+	kind := strings.ToLower(cmd)
+	ps.waiting = make(map[string]bool, len(names))
+	for _, name := range names {
+		ps.waiting[kind+" "+name] = true
+	}
+	ps.acked, ps.ackErr = make(chan struct{}), nil
+	acked := ps.acked
+	select {
+	case ps.wait <- struct{}{}:
+	default:
+	}
+	if ps.c != nil { // or sent on reconnect
+		ps.write(cmd, stringArgs(names)...)
+	}
+	ps.mu.Unlock()
+
+	select {
+	case <-acked:
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		return ps.ackErr
+	case <-ps.done:
+		return ErrClosed
+	case <-ps.client.Context().Done():
+		ps.mu.Lock()
+		if ps.acked == acked { // not waiting any more, for the reader to block on delivery
+			ps.waiting = nil
+		}
+		ps.mu.Unlock()
+		return ps.client.Context().Err()
+	}
+}
+
+// write sends a command, a broken connection is closed, for the reader to
+// dial again. ps.mu is held.
+func (ps *PubSub) write(cmd string, args ...[]byte) {
+	ps.c.wbuf.encodeRequest(cmd, args)
+	if err := ps.c.flush(); err != nil {
+		ps.c.conn.Close()
+	}
+}
+
+func stringArgs(s []string) [][]byte {
+	args := make([][]byte, len(s))
+	for i, v := range s {
+		args[i] = []byte(v)
+	}
+	return args
+}
+
+// ack marks a confirmation received, or fails the waiting command
+func (ps *PubSub) ack(key string, err error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.waiting == nil {
+		return
+	}
+	delete(ps.waiting, key)
+	if len(ps.waiting) == 0 || err != nil {
+		ps.ackErr = err
+		ps.waiting = nil
+		close(ps.acked)
+	}
+}
+
+func (ps *PubSub) ping() {
+	ticker := time.NewTicker(ps.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ps.done:
+			return
+		case <-ticker.C:
+		}
+		ps.mu.Lock()
+		if ps.c != nil {
+			ps.write("PING")
+		}
+		ps.mu.Unlock()
+	}
+}
+
+// run reads messages, until closed
+func (ps *PubSub) run(c *RedisConn) {
+	defer close(ps.ch)
+	for {
+		m, err := ps.receive(c)
+		if err != nil {
+			c.conn.Close()
+			if c = ps.reconnect(); c == nil {
+				return
+			}
+			continue
+		}
+		if m != nil {
+			ps.pending = append(ps.pending, m)
+		}
+		if len(ps.pending) > 0 && !ps.deliver() {
+			return
+		}
+	}
+}
+
+// receive returns a message, or nil for other replies
+func (ps *PubSub) receive(c *RedisConn) (*Message, error) {
+	c.rbuf.compact()
+	if err := c.setDeadline(false, 2*ps.opts.PingInterval); err != nil {
+		return nil, err
+	}
+	r, err := c.readResponse()
+	if err != nil {
+		if _, ok := err.(RedisError); ok { // eg: SSUBSCRIBE before redis 7
+			ps.ack("", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var values []interface{}
+	switch v := r.(type) {
+	case Push: // RESP3
+		values = v
+	case []interface{}:
+		values = v
+	}
+	if len(values) < 2 {
+		return nil, nil // PONG of RESP3
+	}
+	kind, _ := String(values[0], nil)
+	bulk := func(i int) string { b, _ := values[i].([]byte); return string(b) }
+	switch kind {
+	case "message", "smessage":
+		if len(values) == 3 {
+			b, _ := values[2].([]byte)
+			return &Message{Kind: kind, Channel: bulk(1), Payload: copyBytes(b)}, nil
+		}
+	case "pmessage":
+		if len(values) == 4 {
+			b, _ := values[3].([]byte)
+			return &Message{Kind: kind, Pattern: bulk(1), Channel: bulk(2), Payload: copyBytes(b)}, nil
+		}
+	case "pong":
+	default: // confirmation of (un)subscribe
+		ps.ack(kind+" "+bulk(1), nil)
+	}
+	return nil, nil
+}
+
+// deliver sends the pending messages, or drops one after SendTimeout, and
+// returns false if closed. While an (un)subscribe is waiting, eg: called by
+// the goroutine receiving from Channel, it does not block, to read on for
+// the confirmation.
+func (ps *PubSub) deliver() bool {
+	for len(ps.pending) > 0 {
+		select {
+		case ps.ch <- ps.pending[0]:
+		default:
+			if ps.isWaiting() {
+				return true
+			}
+			switch ps.send(ps.pending[0]) {
+			case sendClosed:
+				return false
+			case sendWoken:
+				continue // waiting, or confirmed already
+			}
+		}
+		ps.pending[0] = nil
+		ps.pending = ps.pending[1:]
+	}
+	ps.pending = nil
+	return true
+}
+
+const (
+	sent = iota // or dropped
+	sendWoken
+	sendClosed
+)
+
+// send blocks until m is sent, dropped after SendTimeout, or an
+// (un)subscribe starts waiting
+func (ps *PubSub) send(m *Message) int {
+	var timeout <-chan time.Time
+	if ps.opts.SendTimeout > 0 {
+		timer := time.NewTimer(ps.opts.SendTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case ps.ch <- m:
+	case <-timeout:
+		ps.dropped.Add(1)
+	case <-ps.wait:
+		return sendWoken
+	case <-ps.done:
+		return sendClosed
+	}
+	return sent
+}
+
+func (ps *PubSub) isWaiting() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.waiting != nil
+}
+
+// reconnect dials, backing off, until connected and subscribed again.
+// Returns nil if closed.
+func (ps *PubSub) reconnect() *RedisConn {
+	ps.mu.Lock()
+	ps.c = nil
+	ps.mu.Unlock()
+	for i := 0; ; i++ {
+		timer := time.NewTimer(ps.client.retryBackoff(i))
+		select {
+		case <-timer.C:
+		case <-ps.done:
+			timer.Stop()
+			return nil
+		}
+		c, err := ps.client.openConn(context.Background())
+		if err != nil {
+			continue
+		}
+
+		ps.mu.Lock()
+		if ps.closed {
+			ps.mu.Unlock()
+			c.conn.Close()
+			return nil
+		}
+		c.wbuf.pos = 0
+		for cmd, set := range ps.subs {
+			if len(set) > 0 {
+				names := make([][]byte, 0, len(set))
+				for name := range set {
+					names = append(names, []byte(name))
+				}
+				c.wbuf.appendRequest(cmd, names)
+			}
+		}
+		if err = c.flush(); err == nil {
+			ps.c = c
+			if ps.waiting != nil { // done by the commands above
+				ps.waiting = nil
+				close(ps.acked)
+			}
+		}
+		ps.mu.Unlock()
+		if err == nil {
+			return c
+		}
+		c.conn.Close()
+	}
+}
+
+// Close closes the connection, and the channel of messages
+func (ps *PubSub) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return nil
+	}
+	ps.closed = true
+	close(ps.done)
+	if ps.c != nil {
+		ps.c.conn.Close()
+	}
+	return nil
+}
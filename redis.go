@@ -1,44 +1,15 @@
 package redis
 
-import "strconv"
+import (
+	"errors"
+	"strconv"
+)
 
 const (
 	DefaultMaxCon = 5
 	BufferSize    = 1024 * 2
 )
 
-type RedisError string
-
-func (err RedisError) Error() string { return "Redis Error: " + string(err) }
-
-var KeyDoesNotExist = RedisError("Key does not exist")
-
-// var hsetKey = RedisError("Key does not exist")
-
-func (client *Client) Hgetall(key string) (m map[string]string, err error) {
-	rets, err := client.sendCommand("HGETALL", true, []byte(key))
-	if err != nil {
-		return m, err
-	}
-
-	for i := 0; i < len(rets.([]interface{})); i += 2 {
-		// m[rets
-	}
-	return m, err
-}
-
-func (client *Client) Sadd(key string, data interface{}) (bool, error) {
-	v, err := client.sendCommand("SADD", false, []byte(key), toBytes(data))
-	if err != nil {
-		return false, err
-	}
-	return v.(int) == 1, nil
-}
-
-func (client *Client) Smembers(key string) ([]string, error) {
-	return client.listCommand("SMEMBERS", []byte(key))
-}
-
 func (client *Client) Ltrim(key string, start, end int) error {
 	return client.simple("LTRIM", []byte(key), toBytes(start), toBytes(end))
 }
@@ -48,11 +19,7 @@ func (client *Client) Lrange(key string, start, stop int) ([]string, error) {
 }
 
 func (client *Client) Setnx(key string, data interface{}) (bool, error) {
-	v, err := client.sendCommand("SETNX", false, []byte(key), toBytes(data))
-	if err != nil {
-		return false, err
-	}
-	return v.(int) == 1, nil
+	return Bool(client.sendCommand("SETNX", false, []byte(key), toBytes(data)))
 }
 
 func (client *Client) Get(key string) ([]byte, error) {
@@ -116,11 +83,11 @@ func (client *Client) Blpop(lists interface{}, seconds int) ([]byte, string, err
 	return client.blockPop("BLPOP", lists, seconds)
 }
 
-func (client *Client) Lpush(key string, values ...interface{}) (int, error) {
+func (client *Client) Lpush(key string, values ...interface{}) (int64, error) {
 	return client.listPush("LPUSH", key, values)
 }
 
-func (client *Client) Rpush(key string, values ...interface{}) (int, error) {
+func (client *Client) Rpush(key string, values ...interface{}) (int64, error) {
 	return client.listPush("RPUSH", key, values)
 }
 
@@ -135,9 +102,6 @@ func (client *Client) Pipeline() (*Pipeline, error) {
 }
 
 func (client *Client) GetString(key string) (string, error) {
-	c, err := client.getCon()
-	defer client.returnCon(c)
-
 	value, err := client.sendCommand("GET", false, []byte(key))
 	if err != nil {
 		return "", err
@@ -152,7 +116,14 @@ func (client *Client) Ping() error {
 	return client.simple("PING")
 }
 
+var errSelect = errors.New("Redis Error: Select of a pooled Client, use WithDB or Conn")
+
+// Select switches the db of a Conn. A Client fails, as a connection of the
+// pool can't be switched, use WithDB instead.
 func (client *Client) Select(db int) error {
+	if client.pinned == nil {
+		return errSelect
+	}
 	return client.simple("SELECT", []byte(strconv.Itoa(db)))
 }
 
@@ -163,14 +134,3 @@ func (client *Client) Setex(key string, seconds int, data interface{}) error {
 func (client *Client) Set(key string, data interface{}) error {
 	return client.simple("SET", []byte(key), toBytes(data))
 }
-
-func (client *Client) Del(key string) error {
-	return client.simple("Del", []byte(key))
-}
-
-func (client *Client) Hmset(key string, mapping map[string]interface{}) error {
-	var args [][]byte
-	args = append(args, []byte(key))
-
-	return client.simple("HMSET", args...)
-}
@@ -146,6 +146,34 @@ func BenchmarkHmset(b *testing.B) {
 	client.Del(myKey)
 }
 
+func benchmarkParallelGet(b *testing.B, opts *Options) {
+	c, err := NewClientWithOptions(opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	c.Set(myKey, myValue)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Get(myKey)
+		}
+	})
+	b.StopTimer()
+	c.Del(myKey)
+}
+
+// goroutines checking out a connection each, vs sharing one
+func BenchmarkParallelGetPool(b *testing.B) {
+	b.SetParallelism(16)
+	benchmarkParallelGet(b, &Options{Addr: TestServer, MaxCon: 64})
+}
+
+func BenchmarkParallelGetMultiplex(b *testing.B) {
+	b.SetParallelism(16)
+	benchmarkParallelGet(b, &Options{Addr: TestServer, Multiplex: 1})
+}
+
 func BenchmarkRawConnPing(b *testing.B) {
 	c, err := net.Dial("tcp", TestServer)
 
@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Do sends any command. args are []byte, strings, numbers, a time.Duration in
+// nanoseconds, bools as 1 or 0, or nil as an empty string, other types are
+// an error, and the command is not sent. The reply is
+// one of: []byte for status and bulk replies, int64 for integer replies,
+// []interface{} for multi-bulk replies, and nil for NULL. With RESP3, also
+// map[string]interface{} for maps, float64 for doubles, bool for booleans and
+// *big.Int for big numbers. An element of an aggregate may be a RedisError,
+// eg: of EXEC. The helpers below convert it,
+// eg: redis.Int64(client.Do("INCR", key))
+func (client *Client) Do(cmd string, args ...interface{}) (interface{}, error) {
+	bs, err := toArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return client.sendCommand(strings.ToUpper(cmd), true, bs...)
+}
+
+func Int64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case *big.Int:
+		if v.IsInt64() {
+			return v.Int64(), nil
+		}
+	case nil:
+		return 0, KeyDoesNotExist
+	}
+	return 0, fmt.Errorf("Unexpected type %T for Int64", reply)
+}
+
+func Float64(reply interface{}, err error) (float64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case nil:
+		return 0, KeyDoesNotExist
+	}
+	return 0, fmt.Errorf("Unexpected type %T for Float64", reply)
+}
+
+// Bool converts an integer reply, or a status reply such as OK, to a bool
+func Bool(reply interface{}, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v != 0, nil
+	case []byte:
+		return string(v) == "OK" || string(v) == "1", nil
+	case bool:
+		return v, nil
+	case nil:
+		return false, KeyDoesNotExist
+	}
+	return false, fmt.Errorf("Unexpected type %T for Bool", reply)
+}
+
+func Bytes(reply interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case *big.Int:
+		return []byte(v.String()), nil
+	case nil:
+		return nil, KeyDoesNotExist
+	}
+	return nil, fmt.Errorf("Unexpected type %T for Bytes", reply)
+}
+
+func String(reply interface{}, err error) (string, error) {
+	bs, err := Bytes(reply, err)
+	return string(bs), err
+}
+
+// Values converts a multi-bulk reply, an empty one is nil. A RESP3 map is
+// flattened to key value pairs, as replied by RESP2
+func Values(reply interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		rets := make([]interface{}, 0, len(v)*2)
+		for k, e := range v {
+			rets = append(rets, []byte(k), e)
+		}
+		return rets, nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Unexpected type %T for Values", reply)
+}
+
+// Strings converts a multi-bulk reply, NULL elements are ""
+func Strings(reply interface{}, err error) ([]string, error) {
+	values, err := Values(reply, err)
+	if err != nil || values == nil {
+		return nil, err
+	}
+	rets := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if rets[i], err = String(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return rets, nil
+}
+
+// StringMap converts a multi-bulk reply of field value pairs, eg: HGETALL
+func StringMap(reply interface{}, err error) (map[string]string, error) {
+	values, err := Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("Odd number of elements %d for StringMap", len(values))
+	}
+	m := make(map[string]string, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		m[values[i]] = values[i+1]
+	}
+	return m, nil
+}
@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"iter"
+	"strconv"
+)
+
+// ScanIterator iterates the elements replied by a command of the SCAN
+// family, a page at a time, until the cursor is back to 0. An element may be
+// returned more than once, if the keyspace is changed during the iteration.
+// Check Err after the iteration.
+type ScanIterator struct {
+	client     *Client
+	cmd, key   string
+	match, typ string
+	count      int64
+	err        error
+}
+
+// Scan returns keys of an iteration, and the next cursor, see Hscan. typ, if
+// not empty, filters keys by type, eg: hash
+func (client *Client) Scan(cursor uint64, match string, count int64, typ string) ([]string, uint64, error) {
+	return client.scan("SCAN", "", cursor, match, count, typ)
+}
+
+// ZscanIterator iterates members and scores of a sorted set, see ScanIterator
+type ZscanIterator struct {
+	it ScanIterator
+}
+
+// Zscan returns members and scores of an iteration, and the next cursor
+func (client *Client) Zscan(key string, cursor uint64, match string, count int64) ([]Z, uint64, error) {
+	page, cursor, err := client.scan("ZSCAN", key, cursor, match, count, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	zs, err := parseZscan(page)
+	return zs, cursor, err
+}
+
+func parseZscan(page []string) ([]Z, error) {
+	zs := make([]Z, len(page)/2)
+	for i := range zs {
+		score, err := strconv.ParseFloat(page[2*i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		zs[i] = Z{Member: page[2*i], Score: score}
+	}
+	return zs, nil
+}
+
+// ScanIter iterates keys of the db, eg:
+//
+//	it := client.ScanIter("session:*", 100, "")
+//	for key := range it.All() {
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (client *Client) ScanIter(match string, count int64, typ string) *ScanIterator {
+	return &ScanIterator{client: client, cmd: "SCAN", match: match, count: count, typ: typ}
+}
+
+// SscanIter iterates members of a set
+func (client *Client) SscanIter(key, match string, count int64) *ScanIterator {
+	return &ScanIterator{client: client, cmd: "SSCAN", key: key, match: match, count: count}
+}
+
+// HscanIter iterates fields and values of a hash, use Pairs
+func (client *Client) HscanIter(key, match string, count int64) *ScanIterator {
+	return &ScanIterator{client: client, cmd: "HSCAN", key: key, match: match, count: count}
+}
+
+// ZscanIter iterates members and scores of a sorted set
+func (client *Client) ZscanIter(key, match string, count int64) *ZscanIterator {
+	return &ZscanIterator{it: ScanIterator{client: client, cmd: "ZSCAN", key: key, match: match, count: count}}
+}
+
+// All iterates the elements, fields and values of HSCAN are alternate
+// elements
+func (it *ScanIterator) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		it.pages(func(page []string) bool {
+			for _, e := range page {
+				if !yield(e) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Pairs iterates fields and values of HSCAN
+func (it *ScanIterator) Pairs() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		it.pages(func(page []string) bool {
+			for i := 0; i+1 < len(page); i += 2 {
+				if !yield(page[i], page[i+1]) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Err returns the error that stopped the last iteration, nil if completed, or
+// stopped by the loop
+func (it *ScanIterator) Err() error {
+	return it.err
+}
+
+// pages calls f with each page, until f returns false
+func (it *ScanIterator) pages(f func(page []string) bool) {
+	it.err = nil
+	for cursor := uint64(0); ; {
+		page, next, err := it.client.scan(it.cmd, it.key, cursor, it.match, it.count, it.typ)
+		if err != nil {
+			it.err = err
+			return
+		}
+		if !f(page) || next == 0 {
+			return
+		}
+		cursor = next
+	}
+}
+
+// Pairs iterates members and scores
+func (it *ZscanIterator) Pairs() iter.Seq2[string, float64] {
+	return func(yield func(string, float64) bool) {
+		it.it.pages(func(page []string) bool {
+			zs, err := parseZscan(page)
+			if err != nil {
+				it.it.err = err
+				return false
+			}
+			for _, z := range zs {
+				if !yield(z.Member, z.Score) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+func (it *ZscanIterator) Err() error {
+	return it.it.err
+}
@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// Script is a Lua script, run by its SHA1 with EVALSHA, and sent by EVAL
+// only if it's not loaded yet, eg:
+//
+//	var incrBy = redis.NewScript(`return redis.call("INCRBY", KEYS[1], ARGV[1])`)
+//	n, err := redis.Int64(incrBy.Run(client, []string{key}, 2))
+type Script struct {
+	src  string
+	hash string
+}
+
+func NewScript(src string) *Script {
+	h := sha1.Sum([]byte(src))
+	return &Script{src: src, hash: hex.EncodeToString(h[:])}
+}
+
+// Hash returns the SHA1 of the script, in hex
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Load loads the script to the script cache of the server
+func (s *Script) Load(client *Client) error {
+	_, err := client.ScriptLoad(s.src)
+	return err
+}
+
+func (s *Script) Exists(client *Client) (bool, error) {
+	exists, err := client.ScriptExists(s.hash)
+	if err != nil {
+		return false, err
+	}
+	return len(exists) == 1 && exists[0], nil
+}
+
+// Run runs the script by EVALSHA, or by EVAL if NOSCRIPT, which loads it as
+// well. The reply is as of Client.Do.
+func (s *Script) Run(client *Client, keys []string, args ...interface{}) (interface{}, error) {
+	r, err := client.Evalsha(s.hash, keys, args...)
+	if IsNoScript(err) {
+		return client.Eval(s.src, keys, args...)
+	}
+	return r, err
+}
+
+// RunRO runs a read only script like Run, by EVALSHA_RO, redis 7.0
+func (s *Script) RunRO(client *Client, keys []string, args ...interface{}) (interface{}, error) {
+	r, err := client.EvalshaRO(s.hash, keys, args...)
+	if IsNoScript(err) {
+		return client.EvalRO(s.src, keys, args...)
+	}
+	return r, err
+}
+
+// Pipe queues the script to pipe. The first time in pipe, the script is
+// loaded by SCRIPT LOAD before EVALSHA, as NOSCRIPT can't be recovered.
+func (s *Script) Pipe(pipe *Pipeline, keys []string, args ...interface{}) *Reply {
+	all, err := evalArgs(s.hash, keys, args)
+	if err != nil {
+		return &Reply{err: err}
+	}
+	if !pipe.scripts[s.hash] {
+		if pipe.scripts == nil {
+			pipe.scripts = make(map[string]bool)
+		}
+		pipe.queue("SCRIPT", []byte("LOAD"), []byte(s.src))
+		pipe.scripts[s.hash] = true
+	}
+	return pipe.queue("EVALSHA", all...)
+}
+
+// Queue queues the script in tx, between Multi and Exec. It's sent by EVAL,
+// as an EVALSHA failed with NOSCRIPT can't be run again in the transaction.
+func (s *Script) Queue(tx *Tx, keys []string, args ...interface{}) error {
+	all := make([]interface{}, 0, 2+len(keys)+len(args))
+	all = append(all, s.src, len(keys))
+	for _, k := range keys {
+		all = append(all, k)
+	}
+	return tx.Queue("EVAL", append(all, args...)...)
+}
+
+func evalArgs(script string, keys []string, args []interface{}) ([][]byte, error) {
+	bs, err := toArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	all := append([][]byte{[]byte(script), toBytes(len(keys))}, stringArgs(keys)...)
+	return append(all, bs...), nil
+}
+
+func (client *Client) eval(cmd, script string, keys []string, args []interface{}) (interface{}, error) {
+	all, err := evalArgs(script, keys, args)
+	if err != nil {
+		return nil, err
+	}
+	return client.sendCommand(cmd, true, all...)
+}
+
+// Eval runs a Lua script, the reply is as of Client.Do
+func (client *Client) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return client.eval("EVAL", script, keys, args)
+}
+
+// Evalsha runs a script loaded, by its SHA1, fails with NOSCRIPT if not
+// loaded, see IsNoScript
+func (client *Client) Evalsha(hash string, keys []string, args ...interface{}) (interface{}, error) {
+	return client.eval("EVALSHA", hash, keys, args)
+}
+
+// EvalRO runs a read only script, it may be sent to a replica, redis 7.0
+func (client *Client) EvalRO(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return client.eval("EVAL_RO", script, keys, args)
+}
+
+func (client *Client) EvalshaRO(hash string, keys []string, args ...interface{}) (interface{}, error) {
+	return client.eval("EVALSHA_RO", hash, keys, args)
+}
+
+// ScriptLoad loads a script without running it, returns the SHA1 of it
+func (client *Client) ScriptLoad(script string) (string, error) {
+	return String(client.sendCommand("SCRIPT", true, []byte("LOAD"), []byte(script)))
+}
+
+// ScriptExists returns whether each of the scripts is loaded
+func (client *Client) ScriptExists(hashes ...string) ([]bool, error) {
+	args := append([][]byte{[]byte("EXISTS")}, stringArgs(hashes)...)
+	values, err := Values(client.sendCommand("SCRIPT", false, args...))
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]bool, len(values))
+	for i, v := range values {
+		if rets[i], err = Bool(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return rets, nil
+}
+
+// ScriptFlush removes all the scripts loaded
+func (client *Client) ScriptFlush() error {
+	return client.simple("SCRIPT", []byte("FLUSH"))
+}
+
+// ScriptKill kills the script running, if it has not written yet
+func (client *Client) ScriptKill() error {
+	return client.simple("SCRIPT", []byte("KILL"))
+}
@@ -0,0 +1,117 @@
+package redis
+
+// Sadd returns the number of members added, not already in the set
+func (client *Client) Sadd(key string, members ...interface{}) (int64, error) {
+	args, err := keyArgs(key, members)
+	if err != nil {
+		return 0, err
+	}
+	return Int64(client.sendCommand("SADD", false, args...))
+}
+
+// Srem returns the number of members removed
+func (client *Client) Srem(key string, members ...interface{}) (int64, error) {
+	args, err := keyArgs(key, members)
+	if err != nil {
+		return 0, err
+	}
+	return Int64(client.sendCommand("SREM", false, args...))
+}
+
+func (client *Client) Smembers(key string) ([]string, error) {
+	return client.listCommand("SMEMBERS", []byte(key))
+}
+
+func (client *Client) Scard(key string) (int64, error) {
+	return Int64(client.sendCommand("SCARD", false, []byte(key)))
+}
+
+func (client *Client) Sismember(key string, member interface{}) (bool, error) {
+	return Bool(client.sendCommand("SISMEMBER", false, []byte(key), toBytes(member)))
+}
+
+// Smismember returns whether each of members is a member
+func (client *Client) Smismember(key string, members ...interface{}) ([]bool, error) {
+	args, err := keyArgs(key, members)
+	if err != nil {
+		return nil, err
+	}
+	values, err := Values(client.sendCommand("SMISMEMBER", false, args...))
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]bool, len(values))
+	for i, v := range values {
+		if rets[i], err = Bool(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return rets, nil
+}
+
+// Spop removes and returns a random member, KeyDoesNotExist if empty
+func (client *Client) Spop(key string) ([]byte, error) {
+	return Bytes(client.sendCommand("SPOP", true, []byte(key)))
+}
+
+// SpopCount removes and returns up to count random members
+func (client *Client) SpopCount(key string, count int) ([]string, error) {
+	return Strings(client.sendCommand("SPOP", true, []byte(key), toBytes(count)))
+}
+
+// Srandmember returns a random member, KeyDoesNotExist if empty
+func (client *Client) Srandmember(key string) ([]byte, error) {
+	return Bytes(client.sendCommand("SRANDMEMBER", true, []byte(key)))
+}
+
+// SrandmemberCount returns up to count distinct random members, or count
+// members which may repeat if count is negative
+func (client *Client) SrandmemberCount(key string, count int) ([]string, error) {
+	return Strings(client.sendCommand("SRANDMEMBER", true, []byte(key), toBytes(count)))
+}
+
+// Smove moves member from src to dst, returns false if not a member of src
+func (client *Client) Smove(src, dst string, member interface{}) (bool, error) {
+	return Bool(client.sendCommand("SMOVE", false, []byte(src), []byte(dst), toBytes(member)))
+}
+
+func (client *Client) Sinter(keys ...string) ([]string, error) {
+	return Strings(client.sendCommand("SINTER", true, stringArgs(keys)...))
+}
+
+func (client *Client) Sunion(keys ...string) ([]string, error) {
+	return Strings(client.sendCommand("SUNION", true, stringArgs(keys)...))
+}
+
+// Sdiff returns members of the first set, not in the others
+func (client *Client) Sdiff(keys ...string) ([]string, error) {
+	return Strings(client.sendCommand("SDIFF", true, stringArgs(keys)...))
+}
+
+// Sinterstore stores the intersection in dst, returns the size of it
+func (client *Client) Sinterstore(dst string, keys ...string) (int64, error) {
+	return Int64(client.sendCommand("SINTERSTORE", false, stringArgs(append([]string{dst}, keys...))...))
+}
+
+func (client *Client) Sunionstore(dst string, keys ...string) (int64, error) {
+	return Int64(client.sendCommand("SUNIONSTORE", false, stringArgs(append([]string{dst}, keys...))...))
+}
+
+func (client *Client) Sdiffstore(dst string, keys ...string) (int64, error) {
+	return Int64(client.sendCommand("SDIFFSTORE", false, stringArgs(append([]string{dst}, keys...))...))
+}
+
+// Sintercard returns the size of the intersection, counting up to limit,
+// 0 for no limit
+func (client *Client) Sintercard(limit int, keys ...string) (int64, error) {
+	args := append([][]byte{toBytes(len(keys))}, stringArgs(keys)...)
+	if limit > 0 {
+		args = append(args, []byte("LIMIT"), toBytes(limit))
+	}
+	return Int64(client.sendCommand("SINTERCARD", false, args...))
+}
+
+// Sscan returns members of an iteration, and the next cursor, see Hscan
+func (client *Client) Sscan(key string, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	return client.scan("SSCAN", key, cursor, match, count, "")
+}
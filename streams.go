@@ -0,0 +1,558 @@
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+// XMessage is an entry of a stream
+type XMessage struct {
+	ID     string
+	Values map[string]string
+}
+
+// XStream is the entries read from a stream
+type XStream struct {
+	Stream   string
+	Messages []XMessage
+}
+
+type XaddArgs struct {
+	Stream     string
+	ID         string // * if empty, generated by redis
+	NoMkStream bool   // fail, rather than create the stream
+	// Trim the stream to MaxLen entries, or entries with IDs >= MinID.
+	// Approx trims at most Limit entries (0 for the default of redis) by
+	// removing whole nodes, which is much more efficient.
+	MaxLen int64
+	MinID  string
+	Approx bool
+	Limit  int64
+	// Values are encoded as by Client.Do, or as json if not a string,
+	// []byte, number or bool, eg: a struct. The fields are added in the
+	// random order of the map, the order is not kept.
+	Values map[string]interface{}
+}
+
+// XreadArgs reads entries with IDs greater than IDs, one per stream, or
+// new entries only if IDs is nil. Block > 0 waits up to Block for entries,
+// Block < 0 waits forever, 0 doesn't block.
+type XreadArgs struct {
+	Streams []string
+	IDs     []string
+	Count   int64
+	Block   time.Duration
+}
+
+// XreadgroupArgs reads entries never delivered to other consumers of the
+// group if IDs is nil, or the pending ones of the consumer, eg: IDs of 0.
+type XreadgroupArgs struct {
+	Group    string
+	Consumer string
+	Streams  []string
+	IDs      []string
+	Count    int64
+	Block    time.Duration // see XreadArgs
+	NoAck    bool          // acknowledged once read
+}
+
+// XPending is the summary of entries delivered but not acknowledged
+type XPending struct {
+	Count     int64
+	Lower     string
+	Higher    string
+	Consumers map[string]int64 // number of pending entries by consumer
+}
+
+type XpendingExtArgs struct {
+	Stream   string
+	Group    string
+	Idle     time.Duration // idle at least, redis 6.2
+	Start    string        // - if empty
+	End      string        // + if empty
+	Count    int64
+	Consumer string // all consumers if empty
+}
+
+type XPendingEntry struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration // since delivered
+	RetryCount int64         // times delivered
+}
+
+// XclaimArgs transfers pending entries idle at least MinIdle to Consumer
+type XclaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+	MinIdle  time.Duration
+	IDs      []string
+}
+
+// XautoclaimArgs claims like XclaimArgs, scanning pending entries from
+// Start (0-0 if empty), up to Count (100 by redis if 0)
+type XautoclaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+	MinIdle  time.Duration
+	Start    string
+	Count    int64
+}
+
+type XInfoStream struct {
+	Length          int64
+	RadixTreeKeys   int64
+	RadixTreeNodes  int64
+	Groups          int64
+	LastGeneratedID string
+	FirstEntry      *XMessage // nil if empty
+	LastEntry       *XMessage
+}
+
+type XInfoGroup struct {
+	Name            string
+	Consumers       int64
+	Pending         int64
+	LastDeliveredID string
+}
+
+type XInfoConsumer struct {
+	Name    string
+	Pending int64
+	Idle    time.Duration
+}
+
+// Xadd returns the ID of the added entry
+func (client *Client) Xadd(a *XaddArgs) (string, error) {
+	args := [][]byte{[]byte(a.Stream)}
+	if a.NoMkStream {
+		args = append(args, []byte("NOMKSTREAM"))
+	}
+	args = appendTrim(args, a.MaxLen, a.MinID, a.Approx, a.Limit)
+	id := a.ID
+	if id == "" {
+		id = "*"
+	}
+	args = append(args, []byte(id))
+	for k, v := range a.Values {
+		b, err := valueBytes(v)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, []byte(k), b)
+	}
+	return String(client.sendCommand("XADD", true, args...))
+}
+
+// appendTrim appends MAXLEN or MINID, if any, with ~ and LIMIT if approx
+func appendTrim(args [][]byte, maxLen int64, minID string, approx bool, limit int64) [][]byte {
+	if maxLen > 0 {
+		args = append(args, []byte("MAXLEN"))
+	} else if minID != "" {
+		args = append(args, []byte("MINID"))
+	} else {
+		return args
+	}
+	if approx {
+		args = append(args, []byte("~"))
+	}
+	if maxLen > 0 {
+		args = append(args, toBytes(maxLen))
+	} else {
+		args = append(args, []byte(minID))
+	}
+	if approx && limit > 0 {
+		args = append(args, []byte("LIMIT"), toBytes(limit))
+	}
+	return args
+}
+
+func (client *Client) Xlen(stream string) (int64, error) {
+	return Int64(client.sendCommand("XLEN", false, []byte(stream)))
+}
+
+// Xrange returns entries with IDs between start and stop inclusive, - and +
+// mean the first and the last
+func (client *Client) Xrange(stream, start, stop string) ([]XMessage, error) {
+	return client.xRange("XRANGE", stream, start, stop, 0)
+}
+
+// XrangeN returns count entries at most
+func (client *Client) XrangeN(stream, start, stop string, count int64) ([]XMessage, error) {
+	return client.xRange("XRANGE", stream, start, stop, count)
+}
+
+// Xrevrange returns entries in reverse order, start is the greater
+func (client *Client) Xrevrange(stream, start, stop string) ([]XMessage, error) {
+	return client.xRange("XREVRANGE", stream, start, stop, 0)
+}
+
+func (client *Client) XrevrangeN(stream, start, stop string, count int64) ([]XMessage, error) {
+	return client.xRange("XREVRANGE", stream, start, stop, count)
+}
+
+func (client *Client) xRange(cmd, stream, start, stop string, count int64) ([]XMessage, error) {
+	args := [][]byte{[]byte(stream), []byte(start), []byte(stop)}
+	if count > 0 {
+		args = append(args, []byte("COUNT"), toBytes(count))
+	}
+	return parseXMessages(client.sendCommand(cmd, true, args...))
+}
+
+// Xread returns nil if no entries before the block timeout
+func (client *Client) Xread(a *XreadArgs) ([]XStream, error) {
+	var args [][]byte
+	if a.Count > 0 {
+		args = append(args, []byte("COUNT"), toBytes(a.Count))
+	}
+	args, block := appendBlock(args, a.Block)
+	args, err := appendStreams(args, a.Streams, a.IDs, "$")
+	if err != nil {
+		return nil, err
+	}
+	return parseXStreams(client.sendBlocking(block, "XREAD", true, args...))
+}
+
+// Xreadgroup returns nil if no entries before the block timeout
+func (client *Client) Xreadgroup(a *XreadgroupArgs) ([]XStream, error) {
+	args := [][]byte{[]byte("GROUP"), []byte(a.Group), []byte(a.Consumer)}
+	if a.Count > 0 {
+		args = append(args, []byte("COUNT"), toBytes(a.Count))
+	}
+	args, block := appendBlock(args, a.Block)
+	if a.NoAck {
+		args = append(args, []byte("NOACK"))
+	}
+	args, err := appendStreams(args, a.Streams, a.IDs, ">")
+	if err != nil {
+		return nil, err
+	}
+	return parseXStreams(client.sendBlocking(block, "XREADGROUP", true, args...))
+}
+
+// appendBlock appends BLOCK milliseconds, and returns the block of
+// sendBlocking
+func appendBlock(args [][]byte, block time.Duration) ([][]byte, time.Duration) {
+	if block < 0 {
+		return append(args, []byte("BLOCK"), []byte("0")), -1
+	} else if block > 0 {
+		ms := block.Milliseconds()
+		if ms == 0 { // BLOCK 0 is forever
+			ms = 1
+		}
+		return append(args, []byte("BLOCK"), toBytes(ms)), block
+	}
+	return args, 0
+}
+
+// appendStreams appends the streams, and ids of them, or id for all if nil
+func appendStreams(args [][]byte, streams, ids []string, id string) ([][]byte, error) {
+	if ids != nil && len(ids) != len(streams) {
+		return nil, fmt.Errorf("Invalid IDs, %d for %d Streams", len(ids), len(streams))
+	}
+	args = append(args, []byte("STREAMS"))
+	for _, s := range streams {
+		args = append(args, []byte(s))
+	}
+	for i := range streams {
+		if ids != nil {
+			id = ids[i]
+		}
+		args = append(args, []byte(id))
+	}
+	return args, nil
+}
+
+// XgroupCreate creates a group reading entries after start, $ means new
+// entries only, 0 all of them
+func (client *Client) XgroupCreate(stream, group, start string) error {
+	return client.simple("XGROUP", []byte("CREATE"), []byte(stream), []byte(group), []byte(start))
+}
+
+// XgroupCreateMkStream creates the stream as well, if not exists
+func (client *Client) XgroupCreateMkStream(stream, group, start string) error {
+	return client.simple("XGROUP", []byte("CREATE"), []byte(stream), []byte(group), []byte(start),
+		[]byte("MKSTREAM"))
+}
+
+// XgroupDestroy returns the number of destroyed groups, 0 or 1
+func (client *Client) XgroupDestroy(stream, group string) (int64, error) {
+	return Int64(client.sendCommand("XGROUP", false, []byte("DESTROY"), []byte(stream), []byte(group)))
+}
+
+// XgroupDelConsumer returns the number of pending entries the consumer had
+func (client *Client) XgroupDelConsumer(stream, group, consumer string) (int64, error) {
+	return Int64(client.sendCommand("XGROUP", false, []byte("DELCONSUMER"), []byte(stream),
+		[]byte(group), []byte(consumer)))
+}
+
+// Xack returns the number of acknowledged entries
+func (client *Client) Xack(stream, group string, ids ...string) (int64, error) {
+	args := append([][]byte{[]byte(stream), []byte(group)}, stringArgs(ids)...)
+	return Int64(client.sendCommand("XACK", false, args...))
+}
+
+func (client *Client) Xpending(stream, group string) (*XPending, error) {
+	values, err := Values(client.sendCommand("XPENDING", true, []byte(stream), []byte(group)))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("Unexpected reply of XPENDING %v", values)
+	}
+	p := &XPending{Consumers: map[string]int64{}}
+	p.Count, _ = Int64(values[0], nil)
+	p.Lower, _ = String(values[1], nil)
+	p.Higher, _ = String(values[2], nil)
+	consumers, _ := Values(values[3], nil)
+	for _, c := range consumers {
+		if pair, _ := Strings(c, nil); len(pair) == 2 {
+			p.Consumers[pair[0]], _ = Int64([]byte(pair[1]), nil)
+		}
+	}
+	return p, nil
+}
+
+// XpendingExt returns the details of pending entries
+func (client *Client) XpendingExt(a *XpendingExtArgs) ([]XPendingEntry, error) {
+	args := [][]byte{[]byte(a.Stream), []byte(a.Group)}
+	if a.Idle > 0 {
+		args = append(args, []byte("IDLE"), toBytes(a.Idle.Milliseconds()))
+	}
+	start, end := a.Start, a.End
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+	args = append(args, []byte(start), []byte(end), toBytes(a.Count))
+	if a.Consumer != "" {
+		args = append(args, []byte(a.Consumer))
+	}
+	values, err := Values(client.sendCommand("XPENDING", true, args...))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]XPendingEntry, len(values))
+	for i, v := range values {
+		e, _ := Values(v, nil)
+		if len(e) != 4 {
+			return nil, fmt.Errorf("Unexpected entry of XPENDING %v", v)
+		}
+		entries[i].ID, _ = String(e[0], nil)
+		entries[i].Consumer, _ = String(e[1], nil)
+		idle, _ := Int64(e[2], nil)
+		entries[i].Idle = time.Duration(idle) * time.Millisecond
+		entries[i].RetryCount, _ = Int64(e[3], nil)
+	}
+	return entries, nil
+}
+
+func xClaimArgs(a *XclaimArgs) [][]byte {
+	args := [][]byte{[]byte(a.Stream), []byte(a.Group), []byte(a.Consumer),
+		toBytes(a.MinIdle.Milliseconds())}
+	return append(args, stringArgs(a.IDs)...)
+}
+
+// Xclaim returns the claimed entries, deleted ones are skipped
+func (client *Client) Xclaim(a *XclaimArgs) ([]XMessage, error) {
+	return parseXMessages(client.sendCommand("XCLAIM", true, xClaimArgs(a)...))
+}
+
+// XclaimJustID returns the IDs of the claimed entries, the delivery count
+// is not incremented
+func (client *Client) XclaimJustID(a *XclaimArgs) ([]string, error) {
+	return Strings(client.sendCommand("XCLAIM", true, append(xClaimArgs(a), []byte("JUSTID"))...))
+}
+
+// Xautoclaim returns the claimed entries, and the start of the next call,
+// which is 0-0 once all pending entries are scanned
+func (client *Client) Xautoclaim(a *XautoclaimArgs) ([]XMessage, string, error) {
+	start := a.Start
+	if start == "" {
+		start = "0-0"
+	}
+	args := [][]byte{[]byte(a.Stream), []byte(a.Group), []byte(a.Consumer),
+		toBytes(a.MinIdle.Milliseconds()), []byte(start)}
+	if a.Count > 0 {
+		args = append(args, []byte("COUNT"), toBytes(a.Count))
+	}
+	values, err := Values(client.sendCommand("XAUTOCLAIM", true, args...))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(values) < 2 { // a 3rd of deleted IDs since redis 7
+		return nil, "", fmt.Errorf("Unexpected reply of XAUTOCLAIM %v", values)
+	}
+	next, _ := String(values[0], nil)
+	msgs, err := parseXMessages(values[1], nil)
+	return msgs, next, err
+}
+
+func (client *Client) XinfoStream(stream string) (*XInfoStream, error) {
+	m, err := fieldMap(client.sendCommand("XINFO", true, []byte("STREAM"), []byte(stream)))
+	if err != nil {
+		return nil, err
+	}
+	info := &XInfoStream{Length: m.int64("length"), RadixTreeKeys: m.int64("radix-tree-keys"),
+		RadixTreeNodes: m.int64("radix-tree-nodes"), Groups: m.int64("groups"),
+		LastGeneratedID: m.string("last-generated-id")}
+	for name, p := range map[string]**XMessage{"first-entry": &info.FirstEntry, "last-entry": &info.LastEntry} {
+		if m[name] != nil {
+			msg, err := parseXMessage(m[name])
+			if err != nil {
+				return nil, err
+			}
+			*p = &msg
+		}
+	}
+	return info, nil
+}
+
+func (client *Client) XinfoGroups(stream string) ([]XInfoGroup, error) {
+	values, err := Values(client.sendCommand("XINFO", true, []byte("GROUPS"), []byte(stream)))
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]XInfoGroup, len(values))
+	for i, v := range values {
+		m, err := fieldMap(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		groups[i] = XInfoGroup{Name: m.string("name"), Consumers: m.int64("consumers"),
+			Pending: m.int64("pending"), LastDeliveredID: m.string("last-delivered-id")}
+	}
+	return groups, nil
+}
+
+func (client *Client) XinfoConsumers(stream, group string) ([]XInfoConsumer, error) {
+	values, err := Values(client.sendCommand("XINFO", true, []byte("CONSUMERS"), []byte(stream),
+		[]byte(group)))
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]XInfoConsumer, len(values))
+	for i, v := range values {
+		m, err := fieldMap(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		consumers[i] = XInfoConsumer{Name: m.string("name"), Pending: m.int64("pending"),
+			Idle: time.Duration(m.int64("idle")) * time.Millisecond}
+	}
+	return consumers, nil
+}
+
+// XtrimMaxLen trims the stream to maxLen entries, see XaddArgs for approx.
+// Returns the number of deleted entries.
+func (client *Client) XtrimMaxLen(stream string, maxLen int64, approx bool) (int64, error) {
+	args := appendTrim([][]byte{[]byte(stream)}, maxLen, "", approx, 0)
+	if maxLen <= 0 { // MAXLEN 0 empties the stream
+		args = append(args, []byte("MAXLEN"), []byte("0"))
+	}
+	return Int64(client.sendCommand("XTRIM", false, args...))
+}
+
+// XtrimMinID deletes entries with IDs less than minID
+func (client *Client) XtrimMinID(stream string, minID string, approx bool) (int64, error) {
+	args := appendTrim([][]byte{[]byte(stream)}, 0, minID, approx, 0)
+	return Int64(client.sendCommand("XTRIM", false, args...))
+}
+
+// Xdel returns the number of deleted entries
+func (client *Client) Xdel(stream string, ids ...string) (int64, error) {
+	args := append([][]byte{[]byte(stream)}, stringArgs(ids)...)
+	return Int64(client.sendCommand("XDEL", false, args...))
+}
+
+// parseXMessage parses an entry: [id, [field, value, ...]]
+func parseXMessage(v interface{}) (XMessage, error) {
+	entry, _ := v.([]interface{})
+	if len(entry) != 2 {
+		return XMessage{}, fmt.Errorf("Unexpected stream entry %v", v)
+	}
+	id, err := String(entry[0], nil)
+	if err != nil {
+		return XMessage{}, err
+	}
+	values, err := StringMap(entry[1], nil)
+	return XMessage{ID: id, Values: values}, err
+}
+
+// parseXMessages skips nil entries, deleted but still pending
+func parseXMessages(reply interface{}, err error) ([]XMessage, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]XMessage, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		m, err := parseXMessage(v)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// parseXStreams parses [[stream, entries], ...], or a map of RESP3
+func parseXStreams(reply interface{}, err error) ([]XStream, error) {
+	values, err := Values(reply, err)
+	if err != nil || values == nil {
+		return nil, err
+	}
+	if _, ok := reply.(map[string]interface{}); ok { // flattened
+		pairs := make([]interface{}, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			pairs = append(pairs, []interface{}{values[i], values[i+1]})
+		}
+		values = pairs
+	}
+	streams := make([]XStream, len(values))
+	for i, v := range values {
+		pair, _ := v.([]interface{})
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("Unexpected stream %v", v)
+		}
+		streams[i].Stream, _ = String(pair[0], nil)
+		if streams[i].Messages, err = parseXMessages(pair[1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return streams, nil
+}
+
+// fields is a reply of field value pairs, eg: of XINFO. Missing or
+// unexpected fields are zero.
+type fields map[string]interface{}
+
+func fieldMap(reply interface{}, err error) (fields, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	m := make(fields, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := String(values[i], nil)
+		m[name] = values[i+1]
+	}
+	return m, nil
+}
+
+func (m fields) int64(name string) int64 {
+	n, _ := Int64(m[name], nil)
+	return n
+}
+
+func (m fields) string(name string) string {
+	s, _ := String(m[name], nil)
+	return s
+}
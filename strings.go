@@ -0,0 +1,157 @@
+package redis
+
+import (
+	"reflect"
+	"time"
+)
+
+// SetArgs are options of SET: the key expires after TTL, or at ExpireAt,
+// in milliseconds if not whole seconds. KeepTTL keeps the TTL of the key.
+// NX sets only if the key does not exist, XX only if it exists. A nil
+// *SetArgs is no options.
+type SetArgs struct {
+	TTL      time.Duration
+	ExpireAt time.Time
+	KeepTTL  bool
+	NX, XX   bool
+}
+
+func (a *SetArgs) args() [][]byte {
+	if a == nil {
+		return nil
+	}
+	var args [][]byte
+	if a.TTL > 0 {
+		args = appendTTL(args, "EX", "PX", a.TTL)
+	} else if !a.ExpireAt.IsZero() {
+		if a.ExpireAt.Nanosecond() == 0 {
+			args = append(args, []byte("EXAT"), toBytes(a.ExpireAt.Unix()))
+		} else {
+			args = append(args, []byte("PXAT"), toBytes(a.ExpireAt.UnixMilli()))
+		}
+	} else if a.KeepTTL {
+		args = append(args, []byte("KEEPTTL"))
+	}
+	if a.NX {
+		args = append(args, []byte("NX"))
+	} else if a.XX {
+		args = append(args, []byte("XX"))
+	}
+	return args
+}
+
+// appendTTL appends ex seconds if ttl is whole seconds, or px milliseconds
+func appendTTL(args [][]byte, ex, px string, ttl time.Duration) [][]byte {
+	if ttl%time.Second == 0 {
+		return append(args, []byte(ex), toBytes(int64(ttl/time.Second)))
+	}
+	return append(args, []byte(px), toBytes(ttl.Milliseconds()))
+}
+
+// SetArgs returns whether the key is set, false if aborted by NX or XX
+func (client *Client) SetArgs(key string, data interface{}, a *SetArgs) (bool, error) {
+	args := append([][]byte{[]byte(key), toBytes(data)}, a.args()...)
+	ok, err := Bool(client.sendCommand("SET", false, args...))
+	if err == KeyDoesNotExist {
+		return false, nil
+	}
+	return ok, err
+}
+
+// SetArgsGet sets like SetArgs, and returns the old value, KeyDoesNotExist
+// if none
+func (client *Client) SetArgsGet(key string, data interface{}, a *SetArgs) ([]byte, error) {
+	args := append([][]byte{[]byte(key), toBytes(data)}, a.args()...)
+	return Bytes(client.sendCommand("SET", true, append(args, []byte("GET"))...))
+}
+
+// Getset sets the value, and returns the old one, KeyDoesNotExist if none
+func (client *Client) Getset(key string, data interface{}) ([]byte, error) {
+	return Bytes(client.sendCommand("GETSET", true, []byte(key), toBytes(data)))
+}
+
+// Getdel gets the value and deletes the key
+func (client *Client) Getdel(key string) ([]byte, error) {
+	return Bytes(client.sendCommand("GETDEL", true, []byte(key)))
+}
+
+// Getex gets the value and sets the TTL of the key: ttl > 0 expires it after
+// ttl, ttl < 0 removes the TTL, 0 keeps it
+func (client *Client) Getex(key string, ttl time.Duration) ([]byte, error) {
+	args := [][]byte{[]byte(key)}
+	if ttl > 0 {
+		args = appendTTL(args, "EX", "PX", ttl)
+	} else if ttl < 0 {
+		args = append(args, []byte("PERSIST"))
+	}
+	return Bytes(client.sendCommand("GETEX", true, args...))
+}
+
+// MSet sets the keys of mapping, values are encoded as by Hmset
+func (client *Client) MSet(mapping map[string]interface{}) error {
+	var args [][]byte
+	if err := mappingToArgs(reflect.ValueOf(mapping), &args); err != nil {
+		return err
+	}
+	return client.simple("MSET", args...)
+}
+
+// MSetnx sets the keys only if none of them exists, returns whether set
+func (client *Client) MSetnx(mapping map[string]interface{}) (bool, error) {
+	var args [][]byte
+	if err := mappingToArgs(reflect.ValueOf(mapping), &args); err != nil {
+		return false, err
+	}
+	return Bool(client.sendCommand("MSETNX", false, args...))
+}
+
+// Incr returns the value after the increment
+func (client *Client) Incr(key string) (int64, error) {
+	return Int64(client.sendCommand("INCR", false, []byte(key)))
+}
+
+func (client *Client) Incrby(key string, incr int64) (int64, error) {
+	return Int64(client.sendCommand("INCRBY", false, []byte(key), toBytes(incr)))
+}
+
+func (client *Client) Incrbyfloat(key string, incr float64) (float64, error) {
+	return Float64(client.sendCommand("INCRBYFLOAT", true, []byte(key), toBytes(incr)))
+}
+
+func (client *Client) Decr(key string) (int64, error) {
+	return Int64(client.sendCommand("DECR", false, []byte(key)))
+}
+
+func (client *Client) Decrby(key string, decr int64) (int64, error) {
+	return Int64(client.sendCommand("DECRBY", false, []byte(key), toBytes(decr)))
+}
+
+// Append returns the length of the value after appended
+func (client *Client) Append(key string, data interface{}) (int64, error) {
+	return Int64(client.sendCommand("APPEND", false, []byte(key), toBytes(data)))
+}
+
+func (client *Client) Strlen(key string) (int64, error) {
+	return Int64(client.sendCommand("STRLEN", false, []byte(key)))
+}
+
+// Getrange returns the substring from start to end inclusive, negative
+// offsets are from the end. Empty if the key does not exist.
+func (client *Client) Getrange(key string, start, end int64) ([]byte, error) {
+	return Bytes(client.sendCommand("GETRANGE", true, []byte(key), toBytes(start), toBytes(end)))
+}
+
+// Setrange overwrites from offset, and returns the length of the value
+func (client *Client) Setrange(key string, offset int64, data interface{}) (int64, error) {
+	return Int64(client.sendCommand("SETRANGE", false, []byte(key), toBytes(offset), toBytes(data)))
+}
+
+// Lcs returns the longest common subsequence of the values of two keys
+func (client *Client) Lcs(key1, key2 string) (string, error) {
+	return String(client.sendCommand("LCS", true, []byte(key1), []byte(key2)))
+}
+
+// LcsLen returns the length of the longest common subsequence
+func (client *Client) LcsLen(key1, key2 string) (int64, error) {
+	return Int64(client.sendCommand("LCS", false, []byte(key1), []byte(key2), []byte("LEN")))
+}
@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// returned by EXEC, and Watch after MaxTxRetries
+	ErrTxAborted = errors.New("Redis Error: Transaction aborted, a watched key is modified")
+	errNoMulti   = errors.New("Redis Error: Queue or Exec without Multi")
+)
+
+// Tx is a transaction on a dedicated connection: WATCH keys, read them, then
+// MULTI, Queue commands, and EXEC. Between Multi and Exec, commands should
+// be sent by Queue only. It's not safe for concurrent use.
+type Tx struct {
+	*Conn
+	watching bool
+	multi    bool
+	queued   int
+}
+
+// Tx takes a connection out of the pool, until Tx.Close
+func (client *Client) Tx() (*Tx, error) {
+	conn, err := client.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Conn: conn}, nil
+}
+
+// send sends a command of the transaction. The connection is not dirty
+// after, its state is reset by EXEC, DISCARD, UNWATCH, or by Close
+func (tx *Tx) send(cmd string, args ...[]byte) (interface{}, error) {
+	c := tx.pinned.c
+	if c == nil {
+		return nil, ErrClosed
+	}
+	dirty := c.dirty
+	r, err := tx.sendCommand(cmd, true, args...)
+	c.dirty = dirty
+	return r, err
+}
+
+func (tx *Tx) Watch(keys ...string) error {
+	args := make([][]byte, len(keys))
+	for i, k := range keys {
+		args[i] = []byte(k)
+	}
+	if _, err := tx.send("WATCH", args...); err != nil {
+		return err
+	}
+	tx.watching = true
+	return nil
+}
+
+func (tx *Tx) Unwatch() error {
+	if _, err := tx.send("UNWATCH"); err != nil {
+		return err
+	}
+	tx.watching = false
+	return nil
+}
+
+func (tx *Tx) Multi() error {
+	if _, err := tx.send("MULTI"); err != nil {
+		return err
+	}
+	tx.multi, tx.queued = true, 0
+	return nil
+}
+
+// Queue queues a command between Multi and Exec, args are encoded as by
+// Client.Do. An error, eg: of a wrong number of arguments, aborts the EXEC.
+func (tx *Tx) Queue(cmd string, args ...interface{}) error {
+	if !tx.multi {
+		return errNoMulti
+	}
+	bs, err := toArgs(args)
+	if err != nil {
+		return err
+	}
+	// a queued SELECT, eg, makes the connection dirty
+	if _, err := tx.sendCommand(strings.ToUpper(cmd), true, bs...); err != nil {
+		return err
+	}
+	tx.queued++
+	return nil
+}
+
+// Exec executes the queued commands. The results are those of Client.Do, a
+// result per command, or a RedisError if it failed, the first of which is
+// returned as well. It's ErrTxAborted if a watched key is modified.
+func (tx *Tx) Exec() ([]interface{}, error) {
+	if !tx.multi {
+		return nil, errNoMulti
+	}
+	r, err := tx.send("EXEC")
+	if _, ok := err.(RedisError); ok || err == nil { // discarded, or executed
+		tx.multi, tx.watching = false, false
+	}
+	if err != nil && r == nil {
+		return nil, err
+	}
+	if r == nil && tx.queued > 0 {
+		return nil, ErrTxAborted
+	}
+	rets, _ := r.([]interface{})
+	return rets, err
+}
+
+func (tx *Tx) Discard() error {
+	if _, err := tx.send("DISCARD"); err != nil {
+		return err
+	}
+	tx.multi, tx.watching = false, false
+	return nil
+}
+
+// Close discards the transaction, and returns the connection to the pool
+func (tx *Tx) Close() error {
+	if tx.pinned.c != nil && tx.multi && tx.Discard() != nil {
+		tx.pinned.c.dirty = true
+	}
+	if tx.pinned.c != nil && tx.watching && tx.Unwatch() != nil {
+		tx.pinned.c.dirty = true
+	}
+	return tx.Conn.Close()
+}
+
+// Watch runs fn in a transaction watching keys, which is retried up to
+// Options.MaxTxRetries times if aborted by a modification of them. fn
+// should read keys by tx, and finally call tx.Multi, tx.Queue and tx.Exec,
+// returning the error of Exec, eg:
+//
+//	err := client.Watch([]string{key}, func(tx *redis.Tx) error {
+//		n, err := redis.Int64(tx.Do("GET", key))
+//		if err != nil && err != redis.KeyDoesNotExist {
+//			return err
+//		}
+//		tx.Multi()
+//		tx.Queue("SET", key, n*2)
+//		_, err = tx.Exec()
+//		return err
+//	})
+func (client *Client) Watch(keys []string, fn func(tx *Tx) error) error {
+	for i := 0; ; i++ {
+		err := client.watchOnce(keys, fn)
+		if err != ErrTxAborted || i >= client.opts.MaxTxRetries {
+			return err
+		}
+	}
+}
+
+func (client *Client) watchOnce(keys []string, fn func(tx *Tx) error) error {
+	tx, err := client.Tx()
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	if len(keys) > 0 {
+		if err := tx.Watch(keys...); err != nil {
+			return err
+		}
+	}
+	return fn(tx)
+}
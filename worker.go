@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// StreamWorker processes entries of a stream by a consumer group: Workers
+// goroutines read new entries and call Handler, an entry is acknowledged
+// if Handler returns nil, or delivered again later otherwise. Entries
+// pending for MinIdle, eg: of a crashed consumer, are claimed and handled
+// again every ClaimInterval, or moved to DeadLetter once delivered
+// MaxDeliveries times.
+type StreamWorker struct {
+	Client   *Client
+	Stream   string
+	Group    string // created, reading from the beginning, if not exists
+	Consumer string // workers are named Consumer-0, Consumer-1...
+	Workers  int    // 1 if 0
+
+	// Called for each entry, ctx is not canceled by the shutdown
+	Handler func(ctx context.Context, msg XMessage) error
+	// Errors of Handler and redis, logged if nil
+	OnError func(err error)
+
+	Count int64         // entries read at a time, 10 if 0
+	Block time.Duration // of XREADGROUP, 5s if 0
+
+	ClaimInterval time.Duration // 30s if 0
+	MinIdle       time.Duration // 1 minute if 0
+	// Entries delivered MaxDeliveries times are added to the DeadLetter
+	// stream, with the same values, and acknowledged. 0 means no limit.
+	MaxDeliveries int64
+	DeadLetter    string // Stream + ":dead" if empty
+}
+
+func (w *StreamWorker) init() {
+	if w.Workers == 0 {
+		w.Workers = 1
+	}
+	if w.Count == 0 {
+		w.Count = 10
+	}
+	if w.Block == 0 {
+		w.Block = 5 * time.Second
+	}
+	if w.ClaimInterval == 0 {
+		w.ClaimInterval = 30 * time.Second
+	}
+	if w.MinIdle == 0 {
+		w.MinIdle = time.Minute
+	}
+	if w.DeadLetter == "" {
+		w.DeadLetter = w.Stream + ":dead"
+	}
+	if w.OnError == nil {
+		w.OnError = func(err error) { log.Println("redis stream worker:", err) }
+	}
+}
+
+// Run processes entries until ctx is done, then waits for the entries being
+// handled. Entries read but not handled yet stay pending, to be claimed.
+func (w *StreamWorker) Run(ctx context.Context) error {
+	w.init()
+	err := w.Client.XgroupCreateMkStream(w.Stream, w.Group, "0")
+	if err != nil && ErrorCode(err) != "BUSYGROUP" {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.Workers; i++ {
+		wg.Add(1)
+		go func(consumer string) {
+			defer wg.Done()
+			w.read(ctx, consumer)
+		}(fmt.Sprintf("%s-%d", w.Consumer, i))
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.claimLoop(ctx)
+	}()
+	wg.Wait()
+	return nil
+}
+
+func (w *StreamWorker) read(ctx context.Context, consumer string) {
+	client := w.Client.WithContext(ctx) // a blocking read is interrupted
+	for failures := 0; ctx.Err() == nil; {
+		streams, err := client.Xreadgroup(&XreadgroupArgs{Group: w.Group, Consumer: consumer,
+			Streams: []string{w.Stream}, Count: w.Count, Block: w.Block})
+		if err != nil {
+			if ctx.Err() == nil {
+				w.OnError(err)
+				w.sleep(ctx, w.Client.retryBackoff(failures))
+				failures++
+			}
+			continue
+		}
+		failures = 0
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				if ctx.Err() != nil {
+					return
+				}
+				w.handle(ctx, msg)
+			}
+		}
+	}
+}
+
+// handle calls Handler, and acknowledges the entry on success
+func (w *StreamWorker) handle(ctx context.Context, msg XMessage) {
+	if err := w.Handler(context.WithoutCancel(ctx), msg); err != nil {
+		w.OnError(fmt.Errorf("Handle %s of %s: %w", msg.ID, w.Stream, err))
+		return
+	}
+	if _, err := w.Client.Xack(w.Stream, w.Group, msg.ID); err != nil {
+		w.OnError(err)
+	}
+}
+
+func (w *StreamWorker) sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (w *StreamWorker) claimLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.ClaimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := w.claim(ctx); err != nil && ctx.Err() == nil {
+			w.OnError(err)
+		}
+	}
+}
+
+// claim moves entries delivered too many times to DeadLetter, then
+// claims and handles the other idle ones
+func (w *StreamWorker) claim(ctx context.Context) error {
+	consumer := w.Consumer + "-claim"
+	if w.MaxDeliveries > 0 {
+		for start := "-"; ; {
+			pending, err := w.Client.XpendingExt(&XpendingExtArgs{Stream: w.Stream, Group: w.Group,
+				Idle: w.MinIdle, Start: start, Count: 100})
+			if err != nil {
+				return err
+			}
+			for _, p := range pending {
+				if p.RetryCount >= w.MaxDeliveries {
+					if err := w.deadLetter(consumer, p.ID); err != nil {
+						return err
+					}
+				}
+			}
+			if len(pending) < 100 {
+				break
+			}
+			start = "(" + pending[len(pending)-1].ID // exclusive, redis 6.2
+		}
+	}
+
+	for start := "0-0"; ctx.Err() == nil; {
+		msgs, next, err := w.Client.Xautoclaim(&XautoclaimArgs{Stream: w.Stream, Group: w.Group,
+			Consumer: consumer, MinIdle: w.MinIdle, Start: start, Count: w.Count})
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if ctx.Err() != nil {
+				return nil
+			}
+			w.handle(ctx, msg)
+		}
+		if next == "0-0" {
+			return nil
+		}
+		start = next
+	}
+	return nil
+}
+
+func (w *StreamWorker) deadLetter(consumer, id string) error {
+	// claimed to read the values, it may be deleted from the stream
+	msgs, err := w.Client.Xclaim(&XclaimArgs{Stream: w.Stream, Group: w.Group, Consumer: consumer,
+		MinIdle: w.MinIdle, IDs: []string{id}})
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 { // claimed by others, or deleted
+		return nil
+	}
+	// the order of the fields is lost, as of XMessage.Values
+	values := make(map[string]interface{}, len(msgs[0].Values))
+	for k, v := range msgs[0].Values {
+		values[k] = v
+	}
+	if _, err := w.Client.Xadd(&XaddArgs{Stream: w.DeadLetter, Values: values}); err != nil {
+		return err
+	}
+	_, err = w.Client.Xack(w.Stream, w.Group, id)
+	return err
+}
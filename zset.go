@@ -0,0 +1,331 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Z is a member of a sorted set
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// ZWithKey is a member popped from the sorted set Key
+type ZWithKey struct {
+	Z
+	Key string
+}
+
+// ZaddArgs adds or updates Members: NX only adds new members, XX only
+// updates existing ones, GT and LT only update to a greater or a less score,
+// and CH counts the updated members as well.
+type ZaddArgs struct {
+	NX, XX, GT, LT, CH bool
+	Members            []Z
+}
+
+// ZrangeArgs ranges members by index, or by score if ByScore, eg: 1, (1
+// exclusive, -inf and +inf, or lexicographically if ByLex, eg: [a, (a, - and
+// +. Start is the greater if Rev. LIMIT Offset Count if Count != 0, a
+// negative Count means all after Offset.
+type ZrangeArgs struct {
+	Key         string
+	Start, Stop string
+	ByScore     bool
+	ByLex       bool
+	Rev         bool
+	Offset      int64
+	Count       int64
+}
+
+// ZStore is the sorted sets of ZUNION and ZINTER, scores are multiplied by
+// Weights, if any, and aggregated by Aggregate: SUM (if empty), MIN or MAX
+type ZStore struct {
+	Keys      []string
+	Weights   []float64
+	Aggregate string
+}
+
+func zArgs(key string, members []Z) [][]byte {
+	args := make([][]byte, 0, len(members)*2+1)
+	if key != "" {
+		args = append(args, []byte(key))
+	}
+	for _, z := range members {
+		args = append(args, toBytes(z.Score), []byte(z.Member))
+	}
+	return args
+}
+
+// Zadd returns the number of members added
+func (client *Client) Zadd(key string, members ...Z) (int64, error) {
+	return Int64(client.sendCommand("ZADD", false, zArgs(key, members)...))
+}
+
+// ZaddArgs returns the number of members added, and updated if CH
+func (client *Client) ZaddArgs(key string, a *ZaddArgs) (int64, error) {
+	return Int64(client.sendCommand("ZADD", false, zAddArgs(key, a, false)...))
+}
+
+// ZaddIncr increments the score of the only member, and returns the new
+// score. KeyDoesNotExist if aborted by NX, XX, GT or LT.
+func (client *Client) ZaddIncr(key string, a *ZaddArgs) (float64, error) {
+	return Float64(client.sendCommand("ZADD", true, zAddArgs(key, a, true)...))
+}
+
+func zAddArgs(key string, a *ZaddArgs, incr bool) [][]byte {
+	args := [][]byte{[]byte(key)}
+	for _, f := range []struct {
+		set  bool
+		flag string
+	}{{a.NX, "NX"}, {a.XX, "XX"}, {a.GT, "GT"}, {a.LT, "LT"}, {a.CH, "CH"}, {incr, "INCR"}} {
+		if f.set {
+			args = append(args, []byte(f.flag))
+		}
+	}
+	return append(args, zArgs("", a.Members)...)
+}
+
+// Zincrby returns the new score
+func (client *Client) Zincrby(key string, incr float64, member string) (float64, error) {
+	return Float64(client.sendCommand("ZINCRBY", true, []byte(key), toBytes(incr), []byte(member)))
+}
+
+func (client *Client) Zcard(key string) (int64, error) {
+	return Int64(client.sendCommand("ZCARD", false, []byte(key)))
+}
+
+// Zscore returns KeyDoesNotExist if not a member
+func (client *Client) Zscore(key, member string) (float64, error) {
+	return Float64(client.sendCommand("ZSCORE", true, []byte(key), []byte(member)))
+}
+
+// Zmscore returns the scores of members, nil for those not a member
+func (client *Client) Zmscore(key string, members ...string) ([]*float64, error) {
+	args := append([][]byte{[]byte(key)}, stringArgs(members)...)
+	values, err := Values(client.sendCommand("ZMSCORE", true, args...))
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]*float64, len(values))
+	for i, v := range values {
+		if v != nil {
+			score, err := Float64(v, nil)
+			if err != nil {
+				return nil, err
+			}
+			scores[i] = &score
+		}
+	}
+	return scores, nil
+}
+
+// Zrank returns the index of member, by ascending scores. KeyDoesNotExist
+// if not a member.
+func (client *Client) Zrank(key, member string) (int64, error) {
+	return Int64(client.sendCommand("ZRANK", false, []byte(key), []byte(member)))
+}
+
+// Zrevrank returns the index by descending scores
+func (client *Client) Zrevrank(key, member string) (int64, error) {
+	return Int64(client.sendCommand("ZREVRANK", false, []byte(key), []byte(member)))
+}
+
+// Zrange returns members from index start to stop inclusive, -1 is the last
+func (client *Client) Zrange(key string, start, stop int64) ([]string, error) {
+	return client.ZrangeArgs(&ZrangeArgs{Key: key, Start: strconv.FormatInt(start, 10),
+		Stop: strconv.FormatInt(stop, 10)})
+}
+
+func (client *Client) ZrangeWithScores(key string, start, stop int64) ([]Z, error) {
+	return client.ZrangeArgsWithScores(&ZrangeArgs{Key: key, Start: strconv.FormatInt(start, 10),
+		Stop: strconv.FormatInt(stop, 10)})
+}
+
+func (client *Client) ZrangeArgs(a *ZrangeArgs) ([]string, error) {
+	return Strings(client.sendCommand("ZRANGE", true, zRangeArgs(a)...))
+}
+
+// ZrangeArgsWithScores is ZrangeArgs WITHSCORES, not with ByLex
+func (client *Client) ZrangeArgsWithScores(a *ZrangeArgs) ([]Z, error) {
+	args := append(zRangeArgs(a), []byte("WITHSCORES"))
+	return parseZ(client.sendCommand("ZRANGE", true, args...))
+}
+
+func zRangeArgs(a *ZrangeArgs) [][]byte {
+	args := [][]byte{[]byte(a.Key), []byte(a.Start), []byte(a.Stop)}
+	if a.ByScore {
+		args = append(args, []byte("BYSCORE"))
+	} else if a.ByLex {
+		args = append(args, []byte("BYLEX"))
+	}
+	if a.Rev {
+		args = append(args, []byte("REV"))
+	}
+	if a.Count != 0 {
+		args = append(args, []byte("LIMIT"), toBytes(a.Offset), toBytes(a.Count))
+	}
+	return args
+}
+
+// Zrem returns the number of members removed
+func (client *Client) Zrem(key string, members ...string) (int64, error) {
+	args := append([][]byte{[]byte(key)}, stringArgs(members)...)
+	return Int64(client.sendCommand("ZREM", false, args...))
+}
+
+func (client *Client) Zremrangebyrank(key string, start, stop int64) (int64, error) {
+	return Int64(client.sendCommand("ZREMRANGEBYRANK", false, []byte(key), toBytes(start), toBytes(stop)))
+}
+
+// Zremrangebyscore removes members with scores between min and max, see
+// ZrangeArgs for the format
+func (client *Client) Zremrangebyscore(key, min, max string) (int64, error) {
+	return Int64(client.sendCommand("ZREMRANGEBYSCORE", false, []byte(key), []byte(min), []byte(max)))
+}
+
+func (client *Client) Zremrangebylex(key, min, max string) (int64, error) {
+	return Int64(client.sendCommand("ZREMRANGEBYLEX", false, []byte(key), []byte(min), []byte(max)))
+}
+
+// Zcount returns the number of members with scores between min and max
+func (client *Client) Zcount(key, min, max string) (int64, error) {
+	return Int64(client.sendCommand("ZCOUNT", false, []byte(key), []byte(min), []byte(max)))
+}
+
+func (client *Client) Zlexcount(key, min, max string) (int64, error) {
+	return Int64(client.sendCommand("ZLEXCOUNT", false, []byte(key), []byte(min), []byte(max)))
+}
+
+// Zpopmin removes and returns up to count members of the lowest scores
+func (client *Client) Zpopmin(key string, count int64) ([]Z, error) {
+	return parseZ(client.sendCommand("ZPOPMIN", true, []byte(key), toBytes(count)))
+}
+
+func (client *Client) Zpopmax(key string, count int64) ([]Z, error) {
+	return parseZ(client.sendCommand("ZPOPMAX", true, []byte(key), toBytes(count)))
+}
+
+// Bzpopmin pops from the first non empty sorted set of keys, blocking up to
+// timeout, 0 means forever. Returns nil on timeout.
+func (client *Client) Bzpopmin(timeout time.Duration, keys ...string) (*ZWithKey, error) {
+	return client.bzPop("BZPOPMIN", timeout, keys)
+}
+
+func (client *Client) Bzpopmax(timeout time.Duration, keys ...string) (*ZWithKey, error) {
+	return client.bzPop("BZPOPMAX", timeout, keys)
+}
+
+func (client *Client) bzPop(cmd string, timeout time.Duration, keys []string) (*ZWithKey, error) {
+	args := append(stringArgs(keys), toBytes(timeout.Seconds()))
+	block := timeout
+	if timeout == 0 { // block indefinitely
+		block = -1
+	}
+	values, err := Values(client.sendBlocking(block, cmd, true, args...))
+	if err != nil || values == nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("Unexpected reply of %s %v", cmd, values)
+	}
+	z := &ZWithKey{}
+	z.Key, _ = String(values[0], nil)
+	z.Member, _ = String(values[1], nil)
+	z.Score, err = Float64(values[2], nil)
+	return z, err
+}
+
+func (client *Client) Zunion(a *ZStore) ([]string, error) {
+	return Strings(client.sendCommand("ZUNION", true, zStoreArgs("", a)...))
+}
+
+func (client *Client) ZunionWithScores(a *ZStore) ([]Z, error) {
+	args := append(zStoreArgs("", a), []byte("WITHSCORES"))
+	return parseZ(client.sendCommand("ZUNION", true, args...))
+}
+
+// Zunionstore returns the number of members stored in dest
+func (client *Client) Zunionstore(dest string, a *ZStore) (int64, error) {
+	return Int64(client.sendCommand("ZUNIONSTORE", false, zStoreArgs(dest, a)...))
+}
+
+func (client *Client) Zinter(a *ZStore) ([]string, error) {
+	return Strings(client.sendCommand("ZINTER", true, zStoreArgs("", a)...))
+}
+
+func (client *Client) ZinterWithScores(a *ZStore) ([]Z, error) {
+	args := append(zStoreArgs("", a), []byte("WITHSCORES"))
+	return parseZ(client.sendCommand("ZINTER", true, args...))
+}
+
+func (client *Client) Zinterstore(dest string, a *ZStore) (int64, error) {
+	return Int64(client.sendCommand("ZINTERSTORE", false, zStoreArgs(dest, a)...))
+}
+
+// Zdiff returns members of the first sorted set, not in the others
+func (client *Client) Zdiff(keys ...string) ([]string, error) {
+	return Strings(client.sendCommand("ZDIFF", true, zStoreArgs("", &ZStore{Keys: keys})...))
+}
+
+func (client *Client) ZdiffWithScores(keys ...string) ([]Z, error) {
+	args := append(zStoreArgs("", &ZStore{Keys: keys}), []byte("WITHSCORES"))
+	return parseZ(client.sendCommand("ZDIFF", true, args...))
+}
+
+func (client *Client) Zdiffstore(dest string, keys ...string) (int64, error) {
+	return Int64(client.sendCommand("ZDIFFSTORE", false, zStoreArgs(dest, &ZStore{Keys: keys})...))
+}
+
+// zStoreArgs returns [dest] numkeys keys [WEIGHTS ...] [AGGREGATE ...]
+func zStoreArgs(dest string, a *ZStore) [][]byte {
+	var args [][]byte
+	if dest != "" {
+		args = append(args, []byte(dest))
+	}
+	args = append(args, toBytes(len(a.Keys)))
+	args = append(args, stringArgs(a.Keys)...)
+	if len(a.Weights) > 0 {
+		args = append(args, []byte("WEIGHTS"))
+		for _, w := range a.Weights {
+			args = append(args, toBytes(w))
+		}
+	}
+	if a.Aggregate != "" {
+		args = append(args, []byte("AGGREGATE"), []byte(a.Aggregate))
+	}
+	return args
+}
+
+// parseZ parses member score pairs, flat as RESP2, or nested as RESP3
+func parseZ(reply interface{}, err error) ([]Z, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > 0 {
+		if _, nested := values[0].([]interface{}); !nested {
+			pairs := make([]interface{}, 0, len(values)/2)
+			for i := 0; i+1 < len(values); i += 2 {
+				pairs = append(pairs, []interface{}{values[i], values[i+1]})
+			}
+			values = pairs
+		}
+	}
+	zs := make([]Z, len(values))
+	for i, v := range values {
+		pair, _ := v.([]interface{})
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("Unexpected member and score %v", v)
+		}
+		if zs[i].Member, err = String(pair[0], nil); err != nil {
+			return nil, err
+		}
+		if zs[i].Score, err = Float64(pair[1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return zs, nil
+}